@@ -0,0 +1,191 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Ingredient описывает действующее вещество в одной единице лекарства
+type Ingredient struct {
+	// Название действующего вещества
+	Name string `json:"name"`
+	// Количество вещества в одной таблетке или дозе
+	Amount float64 `json:"amount"`
+	// Единица измерения количества (мг, мкг, МЕ)
+	Unit string `json:"unit"`
+}
+
+// Medicine описывает лекарство из справочника
+type Medicine struct {
+	// Уникальный ID лекарства в справочнике
+	ID string `json:"id"`
+	// Основное название лекарства
+	Name string `json:"name"`
+	// Другие названия, по которым можно найти лекарство
+	Synonyms []string `json:"synonyms,omitempty"`
+	// Действующие вещества
+	Ingredients []Ingredient `json:"ingredients"`
+	// Дозировка для отображения пользователю, например "500 мг"
+	Strength string `json:"strength"`
+	// Лекарственная форма: таблетки, капсулы, сироп и т.д.
+	Form string `json:"form"`
+}
+
+// IngredientNames возвращает названия действующих веществ лекарства
+func (m *Medicine) IngredientNames() []string {
+	names := make([]string, 0, len(m.Ingredients))
+	for _, ingredient := range m.Ingredients {
+		names = append(names, ingredient.Name)
+	}
+	return names
+}
+
+// Catalog хранит справочник лекарств, загруженный из файла
+type Catalog struct {
+	// Путь к файлу справочника
+	path string
+	// Лекарства по ID
+	medicines map[string]*Medicine
+	// Лекарства в порядке из файла, для стабильной выдачи поиска
+	ordered []*Medicine
+	// Мьютекс для безопасной перезагрузки справочника
+	mu sync.RWMutex
+}
+
+// Load загружает справочник лекарств из JSON файла
+func Load(path string) (*Catalog, error) {
+	c := &Catalog{path: path}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload перечитывает справочник из файла
+func (c *Catalog) Reload() error {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть справочник лекарств: %w", err)
+	}
+	defer f.Close()
+
+	var list []*Medicine
+	if err := json.NewDecoder(f).Decode(&list); err != nil {
+		return fmt.Errorf("не удалось прочитать справочник лекарств: %w", err)
+	}
+
+	medicines := make(map[string]*Medicine, len(list))
+	for _, medicine := range list {
+		if medicine.ID == "" || medicine.Name == "" {
+			return fmt.Errorf("в справочнике есть лекарство без ID или названия")
+		}
+		if _, ok := medicines[medicine.ID]; ok {
+			return fmt.Errorf("повторяющийся ID лекарства в справочнике: %s", medicine.ID)
+		}
+		medicines[medicine.ID] = medicine
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.medicines = medicines
+	c.ordered = list
+	return nil
+}
+
+// Get возвращает лекарство по ID
+func (c *Catalog) Get(id string) (*Medicine, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if medicine, ok := c.medicines[id]; ok {
+		return medicine, nil
+	}
+	return nil, fmt.Errorf("лекарство не найдено в справочнике")
+}
+
+// FindByName возвращает лекарство, название или синоним которого совпадает
+// с name без учета регистра
+func (c *Catalog) FindByName(name string) (*Medicine, bool) {
+	name = normalize(name)
+	if name == "" {
+		return nil, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, medicine := range c.ordered {
+		for _, candidate := range append([]string{medicine.Name}, medicine.Synonyms...) {
+			if normalize(candidate) == name {
+				return medicine, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Search ищет лекарства по началу или части названия или синонима.
+// Совпадения по началу названия идут первыми.
+func (c *Catalog) Search(query string, limit int) []*Medicine {
+	query = normalize(query)
+	if query == "" {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	type match struct {
+		medicine *Medicine
+		// 0 - совпадение по началу, 1 - по части названия
+		rank int
+		// Порядок в справочнике
+		index int
+	}
+
+	var matches []match
+	for i, medicine := range c.ordered {
+		rank := -1
+		for _, name := range append([]string{medicine.Name}, medicine.Synonyms...) {
+			name = normalize(name)
+			if strings.HasPrefix(name, query) {
+				rank = 0
+				break
+			}
+			if rank < 0 && strings.Contains(name, query) {
+				rank = 1
+			}
+		}
+		if rank >= 0 {
+			matches = append(matches, match{medicine: medicine, rank: rank, index: i})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank < matches[j].rank
+		}
+		return matches[i].index < matches[j].index
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	result := make([]*Medicine, 0, len(matches))
+	for _, m := range matches {
+		result = append(result, m.medicine)
+	}
+	return result
+}
+
+// normalize приводит строку к нижнему регистру без лишних пробелов
+func normalize(s string) string {
+	s = strings.ToLower(strings.ReplaceAll(s, "ё", "е"))
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,81 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Limit описывает максимальное суточное количество действующего вещества
+type Limit struct {
+	// Максимальное количество вещества в сутки
+	MaxDaily float64 `json:"max_daily"`
+	// Единица измерения, должна совпадать с единицей в справочнике
+	Unit string `json:"unit"`
+}
+
+// DoseLimits хранит суточные ограничения по действующим веществам
+type DoseLimits struct {
+	// Ограничения по нормализованному названию вещества
+	limits map[string]Limit
+}
+
+// LoadLimits загружает суточные ограничения из JSON файла вида
+// {"парацетамол": {"max_daily": 4000, "unit": "мг"}}
+func LoadLimits(path string) (*DoseLimits, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть таблицу суточных доз: %w", err)
+	}
+	defer f.Close()
+
+	var raw map[string]Limit
+	if err := json.NewDecoder(f).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать таблицу суточных доз: %w", err)
+	}
+
+	limits := make(map[string]Limit, len(raw))
+	for ingredient, limit := range raw {
+		if limit.MaxDaily <= 0 {
+			return nil, fmt.Errorf("некорректная суточная доза для %s", ingredient)
+		}
+		limits[normalize(ingredient)] = limit
+	}
+	return &DoseLimits{limits: limits}, nil
+}
+
+// Get возвращает ограничение для действующего вещества
+func (l *DoseLimits) Get(ingredient string) (Limit, bool) {
+	limit, ok := l.limits[normalize(ingredient)]
+	return limit, ok
+}
+
+// Во сколько раз единица измерения больше миллиграмма
+var unitFactors = map[string]float64{
+	"г":   1000,
+	"мг":  1,
+	"мкг": 0.001,
+}
+
+// ConvertAmount переводит количество вещества в другую единицу измерения.
+// Возвращает false, если единицы несовместимы, например МЕ и мг.
+func ConvertAmount(amount float64, from, to string) (float64, bool) {
+	if from == to {
+		return amount, true
+	}
+	fromFactor, ok := unitFactors[normalize(from)]
+	if !ok {
+		return 0, false
+	}
+	toFactor, ok := unitFactors[normalize(to)]
+	if !ok {
+		return 0, false
+	}
+	return amount * fromFactor / toFactor, true
+}
+
+// IngredientKey возвращает название вещества, по которому его количества
+// из разных лекарств суммируются: без учета регистра и различий е и ё
+func IngredientKey(name string) string {
+	return normalize(name)
+}
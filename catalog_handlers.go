@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"take-a-pill/catalog"
+	"take-a-pill/problem"
+	"take-a-pill/storage"
+)
+
+// Сколько лекарств возвращать в подсказках по умолчанию
+const defaultSearchLimit = 10
+
+// Обработчик для подсказок по названию лекарства
+func (s *Server) searchMedicines(w http.ResponseWriter, r *http.Request) {
+	if s.catalog == nil {
+		s.writeProblem(w, r, http.StatusServiceUnavailable, storage.CodeCatalogUnavailable)
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		s.writeMissingParameter(w, r, "q")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			s.writeProblem(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "limit")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]*catalog.Medicine{
+		"medicines": s.catalog.Search(query, limit),
+	})
+}
+
+// Обработчик для получения лекарства из справочника
+func (s *Server) getMedicine(w http.ResponseWriter, r *http.Request) {
+	if s.catalog == nil {
+		s.writeProblem(w, r, http.StatusServiceUnavailable, storage.CodeCatalogUnavailable)
+		return
+	}
+
+	medicineID := r.URL.Query().Get("medicine_id")
+	if medicineID == "" {
+		s.writeMissingParameter(w, r, "medicine_id")
+		return
+	}
+
+	medicine, err := s.catalog.Get(medicineID)
+	if err != nil {
+		s.writeProblem(w, r, http.StatusNotFound, storage.CodeMedicineNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(medicine)
+}
@@ -0,0 +1,56 @@
+// Package clock позволяет подменять текущее время в расписаниях,
+// чтобы проверять их поведение вечером, в полночь и при переводе часов
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock возвращает текущее время
+type Clock interface {
+	Now() time.Time
+}
+
+// System - системные часы
+type System struct{}
+
+// Now возвращает текущее системное время
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+// Fake - часы, время на которых меняется только вручную
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake создает часы, показывающие заданное время
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now возвращает установленное время
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+// Set переводит часы на заданное время
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = now
+}
+
+// Add переводит часы вперед на заданный промежуток
+func (f *Fake) Add(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = f.now.Add(d)
+}
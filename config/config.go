@@ -2,20 +2,147 @@ package config
 
 import "time"
 
+// RateLimit задает ограничение частоты запросов одного клиента
+type RateLimit struct {
+	// Сколько запросов в секунду можно делать в среднем. 0 - без ограничений.
+	Rate float64
+	// Сколько запросов можно сделать подряд
+	Burst int
+}
+
 // Config содержит все настройки сервиса
 type Config struct {
 	ServerPort       string
 	NextTakingPeriod time.Duration
 	DayStartHour     int
 	DayEndHour       int
+	// За сколько дней до окончания запаса предупреждать пользователя
+	LowStockDays int
+	// Путь к таблице взаимодействий лекарств (JSON или CSV)
+	InteractionsFile string
+	// Путь к справочнику лекарств
+	CatalogFile string
+	// Путь к таблице максимальных суточных доз действующих веществ
+	DoseLimitsFile string
+	// Отклонять курс при превышении суточной дозы (иначе только предупреждать)
+	RejectOverDoseLimit bool
+	// Сколько хранить ответы на запросы с ключом идемпотентности
+	IdempotencyTTL time.Duration
+	// Путь к спецификации API
+	OpenAPIFile string
+	// Проверять запросы по спецификации API
+	ValidateRequests bool
+	// Проверять ответы по спецификации API (для тестов, ответы буферизуются)
+	ValidateResponses bool
+	// Адрес gRPC API
+	GRPCPort string
+	// Как часто проверять, не наступило ли время приема
+	DueDosesInterval time.Duration
+	// Сколько последних событий хранить для продолжения потока по Last-Event-ID
+	EventHistorySize int
+	// Как часто отправлять в поток событий комментарий, чтобы соединение не закрылось
+	EventsHeartbeat time.Duration
+	// Минимальный уровень логов: debug, info, warn или error.
+	// На уровне debug в лог попадают ID пользователей и названия лекарств.
+	LogLevel string
+	// Формат логов: text или json
+	LogFormat string
+	// Ключ для хеширования ID пользователей в логах
+	LogHashKey string
+	// Куда отправлять трассировки: "" - никуда, stdout или otlp
+	TraceExporter string
+	// Адрес коллектора OTLP (host:port), по умолчанию из переменных OTEL_EXPORTER_OTLP_*
+	TraceEndpoint string
+	// Сколько ждать заголовки запроса
+	ReadHeaderTimeout time.Duration
+	// Сколько ждать запрос целиком
+	ReadTimeout time.Duration
+	// Сколько можно отправлять ответ. Поток событий этим ограничением не прерывается.
+	WriteTimeout time.Duration
+	// Сколько держать неактивное соединение keep-alive
+	IdleTimeout time.Duration
+	// Сколько ждать завершения запросов при остановке сервиса
+	ShutdownTimeout time.Duration
+	// Сколько /readyz отвечает 503 перед тем, как сервис перестанет
+	// принимать соединения. За это время балансировщик успевает
+	// убрать экземпляр из списка и не шлет на него новые запросы.
+	ShutdownDelay time.Duration
+	// Сертификат и ключ TLS. Если заданы, сервис работает по HTTPS.
+	TLSCertFile string
+	TLSKeyFile  string
+	// Ограничение частоты запросов к маршрутам, для которых не задано свое
+	DefaultRateLimit RateLimit
+	// Ограничения частоты запросов к отдельным маршрутам. Ключ - метод и
+	// шаблон маршрута без версии API, например "POST /schedule".
+	RateLimits map[string]RateLimit
+	// Максимальный размер тела запроса в байтах. 0 - без ограничений.
+	MaxBodyBytes int64
+	// Сколько действующих расписаний может быть у пользователя. 0 - без ограничений.
+	MaxSchedulesPerUser int
+	// Ключи API клиентов. Частота запросов ограничивается по ключу, только
+	// если он есть в этом списке, иначе - по IP адресу.
+	APIKeys []string
+	// Ключи API пользователей: ключ -> ID пользователя. Поток событий
+	// открывается только с таким ключом и только для его пользователя.
+	UserAPIKeys map[string]string
+	// Ключ API администратора для отладочных параметров. Если не задан,
+	// отладочные параметры недоступны.
+	AdminAPIKey string
+	// Как по умолчанию распределять приемы по дню: even, front_loaded или meal_anchored
+	TakingTimesStrategy string
+	// Шаг, до которого округляется время приема
+	TakingTimesGranularity time.Duration
+	// Время приемов пищи от полуночи (breakfast, lunch, dinner), если
+	// пользователь не указал свое
+	MealTimes map[string]time.Duration
+	// На сколько можно сдвинуть курс при объединении напоминаний. 0 - без ограничений.
+	ConsolidationMaxShift time.Duration
 }
 
 // DefaultConfig возвращает конфигурацию по умолчанию
 func DefaultConfig() *Config {
 	return &Config{
-		ServerPort:       ":8081",
-		NextTakingPeriod: time.Hour,
-		DayStartHour:     8,
-		DayEndHour:       22,
+		ServerPort:        ":8081",
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		ShutdownTimeout:   20 * time.Second,
+		ShutdownDelay:     5 * time.Second,
+		DefaultRateLimit:  RateLimit{Rate: 10, Burst: 20},
+		// Создавать расписания и рецепты можно реже, чем читать
+		RateLimits: map[string]RateLimit{
+			"POST /schedule":     {Rate: 0.5, Burst: 10},
+			"POST /prescription": {Rate: 0.5, Burst: 10},
+		},
+		MaxBodyBytes:        1 << 20,
+		MaxSchedulesPerUser: 100,
+		NextTakingPeriod:    time.Hour,
+		DayStartHour:        8,
+		DayEndHour:          22,
+		// Приемы распределяются равномерно с шагом 15 минут
+		TakingTimesStrategy:    "even",
+		TakingTimesGranularity: 15 * time.Minute,
+		// Завтрак, обед и ужин
+		MealTimes: map[string]time.Duration{
+			"breakfast": 8 * time.Hour,
+			"lunch":     13 * time.Hour,
+			"dinner":    19 * time.Hour,
+		},
+		ConsolidationMaxShift: time.Hour,
+		LowStockDays:          3,
+		InteractionsFile:      "data/interactions.json",
+		CatalogFile:           "data/medicines.json",
+		DoseLimitsFile:        "data/dose_limits.json",
+		// По умолчанию превышение суточной дозы не допускается
+		RejectOverDoseLimit: true,
+		IdempotencyTTL:      24 * time.Hour,
+		OpenAPIFile:         "openapi.yaml",
+		GRPCPort:            ":9090",
+		DueDosesInterval:    time.Minute,
+		EventHistorySize:    1000,
+		EventsHeartbeat:     30 * time.Second,
+		LogLevel:            "info",
+		LogFormat:           "text",
 	}
 }
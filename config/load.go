@@ -0,0 +1,206 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Префикс переменных окружения с настройками
+const envPrefix = "TAKE_A_PILL_"
+
+// Load возвращает настройки по умолчанию, измененные переменными окружения
+// TAKE_A_PILL_* и флагами командной строки args. Флаг важнее переменной
+// окружения с тем же именем: флаг -admin-api-key соответствует переменной
+// TAKE_A_PILL_ADMIN_API_KEY. Некорректные значения возвращаются как ошибка.
+func Load(args []string) (*Config, error) {
+	cfg := DefaultConfig()
+
+	l := &loader{fs: flag.NewFlagSet("take-a-pill", flag.ContinueOnError)}
+	l.fs.SetOutput(io.Discard)
+	l.string(&cfg.ServerPort, "port", "адрес REST API")
+	l.string(&cfg.GRPCPort, "grpc-port", "адрес gRPC API")
+	l.string(&cfg.TLSCertFile, "tls-cert", "сертификат TLS")
+	l.string(&cfg.TLSKeyFile, "tls-key", "ключ TLS")
+	l.string(&cfg.AdminAPIKey, "admin-api-key", "ключ API администратора")
+	l.list(&cfg.APIKeys, "api-keys", "ключи API клиентов через запятую")
+	l.mapping(&cfg.UserAPIKeys, "user-api-keys", "ключи API пользователей: ключ=user_id через запятую")
+	l.string(&cfg.LogLevel, "log-level", "уровень логов: debug, info, warn или error")
+	l.string(&cfg.LogFormat, "log-format", "формат логов: text или json")
+	l.string(&cfg.LogHashKey, "log-hash-key", "ключ для хеширования ID пользователей в логах")
+	l.string(&cfg.TraceExporter, "trace-exporter", "куда отправлять трассировки: stdout или otlp")
+	l.string(&cfg.TraceEndpoint, "trace-endpoint", "адрес коллектора OTLP")
+	l.string(&cfg.InteractionsFile, "interactions-file", "таблица взаимодействий лекарств")
+	l.string(&cfg.CatalogFile, "catalog-file", "справочник лекарств")
+	l.string(&cfg.DoseLimitsFile, "dose-limits-file", "таблица максимальных суточных доз")
+	l.string(&cfg.OpenAPIFile, "openapi-file", "спецификация API")
+	l.bool(&cfg.RejectOverDoseLimit, "reject-over-dose-limit", "отклонять курс при превышении суточной дозы")
+	l.bool(&cfg.ValidateRequests, "validate-requests", "проверять запросы по спецификации API")
+	l.duration(&cfg.DueDosesInterval, "due-doses-interval", "как часто проверять время приема")
+	l.duration(&cfg.ShutdownTimeout, "shutdown-timeout", "сколько ждать завершения запросов при остановке")
+	l.duration(&cfg.ShutdownDelay, "shutdown-delay", "сколько отвечать 503 на /readyz перед остановкой")
+	l.int(&cfg.MaxSchedulesPerUser, "max-schedules-per-user", "сколько действующих расписаний может быть у пользователя")
+	if len(l.errs) > 0 {
+		return nil, errors.Join(l.errs...)
+	}
+
+	if err := l.fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			l.fs.SetOutput(os.Stderr)
+			l.fs.PrintDefaults()
+		}
+		return nil, err
+	}
+	if l.fs.NArg() > 0 {
+		return nil, fmt.Errorf("лишние аргументы: %s", strings.Join(l.fs.Args(), " "))
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate проверяет, что настройки можно использовать
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.ServerPort != "", "не задан адрес REST API")
+	check(c.GRPCPort != "", "не задан адрес gRPC API")
+	check((c.TLSCertFile == "") == (c.TLSKeyFile == ""), "сертификат и ключ TLS задаются вместе")
+	for _, path := range []string{c.TLSCertFile, c.TLSKeyFile} {
+		if path != "" {
+			_, err := os.Stat(path)
+			check(err == nil, "файл TLS недоступен: %v", err)
+		}
+	}
+	check(oneOf(c.LogLevel, "debug", "info", "warn", "error"), "неизвестный уровень логов %q", c.LogLevel)
+	check(oneOf(c.LogFormat, "text", "json"), "неизвестный формат логов %q", c.LogFormat)
+	check(oneOf(c.TraceExporter, "", "stdout", "otlp"), "неизвестный способ отправки трассировок %q", c.TraceExporter)
+	check(oneOf(c.TakingTimesStrategy, "even", "front_loaded", "meal_anchored"), "неизвестное распределение приемов %q", c.TakingTimesStrategy)
+	check(c.DueDosesInterval > 0, "интервал проверки времени приема должен быть больше нуля")
+	check(c.ShutdownTimeout > 0, "время ожидания при остановке должно быть больше нуля")
+	check(c.ShutdownDelay >= 0, "задержка перед остановкой не может быть отрицательной")
+	check(c.MaxSchedulesPerUser >= 0, "число расписаний пользователя не может быть отрицательным")
+	check(c.DayStartHour >= 0 && c.DayStartHour < c.DayEndHour && c.DayEndHour <= 24,
+		"некорректные границы дня: %d-%d", c.DayStartHour, c.DayEndHour)
+	for _, key := range c.APIKeys {
+		check(key != "", "пустой ключ API в списке")
+	}
+	for key, userID := range c.UserAPIKeys {
+		check(key != "" && userID != "", "пустой ключ API или ID пользователя в ключах пользователей")
+	}
+	return errors.Join(errs...)
+}
+
+// oneOf проверяет, что значение входит в список допустимых
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+// loader регистрирует флаги, значения по умолчанию которых берутся
+// из переменных окружения
+type loader struct {
+	fs *flag.FlagSet
+	// Ошибки в переменных окружения
+	errs []error
+}
+
+// envName возвращает имя переменной окружения для флага name
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// env возвращает значение переменной окружения для флага name
+func (l *loader) env(name string) (string, bool) {
+	return os.LookupEnv(envName(name))
+}
+
+// parseEnv разбирает переменную окружения для флага name
+func (l *loader) parseEnv(name string, parse func(string) error) {
+	if value, ok := l.env(name); ok {
+		if err := parse(value); err != nil {
+			l.errs = append(l.errs, fmt.Errorf("некорректное значение %s: %w", envName(name), err))
+		}
+	}
+}
+
+func (l *loader) string(p *string, name, usage string) {
+	if value, ok := l.env(name); ok {
+		*p = value
+	}
+	l.fs.StringVar(p, name, *p, usage)
+}
+
+func (l *loader) bool(p *bool, name, usage string) {
+	l.parseEnv(name, func(value string) (err error) {
+		*p, err = strconv.ParseBool(value)
+		return err
+	})
+	l.fs.BoolVar(p, name, *p, usage)
+}
+
+func (l *loader) int(p *int, name, usage string) {
+	l.parseEnv(name, func(value string) (err error) {
+		*p, err = strconv.Atoi(value)
+		return err
+	})
+	l.fs.IntVar(p, name, *p, usage)
+}
+
+func (l *loader) duration(p *time.Duration, name, usage string) {
+	l.parseEnv(name, func(value string) (err error) {
+		*p, err = time.ParseDuration(value)
+		return err
+	})
+	l.fs.DurationVar(p, name, *p, usage)
+}
+
+// list разбирает список через запятую, пустые элементы пропускаются
+func (l *loader) list(p *[]string, name, usage string) {
+	parse := func(value string) error {
+		*p = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*p = append(*p, item)
+			}
+		}
+		return nil
+	}
+	l.parseEnv(name, parse)
+	l.fs.Func(name, usage, parse)
+}
+
+// mapping разбирает пары ключ=значение через запятую
+func (l *loader) mapping(p *map[string]string, name, usage string) {
+	parse := func(value string) error {
+		*p = make(map[string]string)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			key, val, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("ожидалось ключ=значение, получено %q", item)
+			}
+			(*p)[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+		return nil
+	}
+	l.parseEnv(name, parse)
+	l.fs.Func(name, usage, parse)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"take-a-pill/problem"
+)
+
+// versionETag возвращает ETag расписания или настроек заданной версии
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// contentETag возвращает ETag, вычисленный по содержимому ответа
+func contentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// noneMatch проверяет заголовок If-None-Match. Возвращает true, если
+// клиент уже получил ответ с этим ETag и можно вернуть 304.
+func noneMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	// Для If-None-Match используется слабое сравнение
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion возвращает версию расписания или настроек из заголовка If-Match.
+// 0 означает, что заголовка нет или указан "*" и версию проверять не нужно.
+// ETag, который не может совпасть ни с одной версией, дает -1.
+func ifMatchVersion(r *http.Request) int {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0
+	}
+
+	// Для If-Match используется строгое сравнение, слабые ETag не совпадают
+	tag, ok := strings.CutPrefix(header, `"`)
+	if !ok {
+		return -1
+	}
+	tag, ok = strings.CutSuffix(tag, `"`)
+	if !ok {
+		return -1
+	}
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		return -1
+	}
+	return version
+}
+
+// ifMatchTag возвращает ETag из заголовка If-Match без кавычек.
+// Пустая строка означает, что заголовка нет или указан "*" и проверять
+// ничего не нужно. Слабый ETag дает "-", который не совпадет ни с одной меткой.
+func ifMatchTag(r *http.Request) string {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return ""
+	}
+
+	tag, ok := strings.CutPrefix(header, `"`)
+	if !ok {
+		return "-"
+	}
+	tag, ok = strings.CutSuffix(tag, `"`)
+	if !ok || tag == "" {
+		return "-"
+	}
+	return tag
+}
+
+// requireIfMatch отклоняет изменение расписания без заголовка If-Match,
+// чтобы клиент не перезаписал чужие изменения
+func (s *Server) requireIfMatch(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Match") == "" {
+			s.writeProblem(w, r, http.StatusPreconditionRequired, problem.CodePreconditionRequired)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Типы событий сервиса
+const (
+	// Пользователь отметил прием лекарства
+	TypeDoseTaken = "dose.taken"
+	// Запас таблеток скоро закончится
+	TypeLowStock = "inventory.low_stock"
+	// Наступило время приема лекарства
+	TypeDoseDue = "dose.due"
+	// Пользователь создал расписание
+	TypeScheduleCreated = "schedule.created"
+	// Расписание изменено: приостановлено, возобновлено или изменен запас
+	TypeScheduleUpdated = "schedule.updated"
+)
+
+// Event описывает событие, произошедшее в сервисе
+type Event struct {
+	// Тип события
+	Type string `json:"type"`
+	// ID пользователя
+	UserID string `json:"user_id"`
+	// ID расписания, к которому относится событие
+	ScheduleID string `json:"schedule_id,omitempty"`
+	// Время события
+	Time time.Time `json:"time"`
+	// Дополнительные данные события
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// subscription - обработчик событий с номером для отписки
+type subscription struct {
+	id      int
+	handler func(Event)
+}
+
+// Bus рассылает события всем подписчикам
+type Bus struct {
+	// Обработчики событий в порядке подписки
+	subscriptions []subscription
+	// Номер следующей подписки
+	nextID int
+	// Мьютекс для безопасной работы со списком обработчиков
+	mu sync.RWMutex
+}
+
+// NewBus создает новую шину событий
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe добавляет обработчик, который будет вызываться для каждого события.
+// Возвращает функцию для отписки.
+func (b *Bus) Subscribe(handler func(Event)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.subscriptions = append(b.subscriptions, subscription{id: id, handler: handler})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		for i, sub := range b.subscriptions {
+			if sub.id == id {
+				b.subscriptions = append(b.subscriptions[:i:i], b.subscriptions[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish отправляет событие всем подписчикам
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	subscriptions := make([]subscription, len(b.subscriptions))
+	copy(subscriptions, b.subscriptions)
+	b.mu.RUnlock()
+
+	for _, sub := range subscriptions {
+		sub.handler(event)
+	}
+}
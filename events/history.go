@@ -0,0 +1,116 @@
+package events
+
+import "sync"
+
+// Сколько событий может ждать отправки одному подписчику истории.
+// Подписчик, который не успевает их забирать, отключается и должен
+// переподключиться, указав ID последнего полученного события.
+const historySubscriberBuffer = 64
+
+// Record - событие с порядковым номером
+type Record struct {
+	// Порядковый номер события, растет с каждым событием
+	ID uint64 `json:"id"`
+	Event
+}
+
+// historySubscriber получает новые события истории
+type historySubscriber struct {
+	filter  func(Event) bool
+	updates chan Record
+}
+
+// History нумерует события шины и хранит последние из них, чтобы клиент
+// мог после переподключения получить пропущенные события
+type History struct {
+	// Последние события в порядке номеров
+	records []Record
+	// Сколько событий хранить
+	size int
+	// Номер последнего события
+	lastID uint64
+	// Подписчики на новые события
+	subscribers map[int]*historySubscriber
+	// Номер следующей подписки
+	nextSubscriber int
+	// Мьютекс для безопасной работы с историей и подписчиками
+	mu sync.Mutex
+}
+
+// NewHistory создает историю из size последних событий шины
+func NewHistory(bus *Bus, size int) *History {
+	h := &History{
+		size:        size,
+		subscribers: make(map[int]*historySubscriber),
+	}
+	bus.Subscribe(h.append)
+	return h
+}
+
+// append нумерует событие, сохраняет его и рассылает подписчикам
+func (h *History) append(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	record := Record{ID: h.lastID, Event: e}
+	h.records = append(h.records, record)
+	if len(h.records) > h.size {
+		h.records = h.records[len(h.records)-h.size:]
+	}
+
+	for id, sub := range h.subscribers {
+		if sub.filter != nil && !sub.filter(e) {
+			continue
+		}
+		select {
+		case sub.updates <- record:
+		default:
+			// Подписчик не успевает, отключаем его
+			close(sub.updates)
+			delete(h.subscribers, id)
+		}
+	}
+}
+
+// Subscribe возвращает сохраненные события после lastID и канал новых событий.
+// Подходят только события, для которых filter возвращает true (nil - все).
+// complete равен false, если часть событий после lastID уже не хранится
+// и клиенту нужно заново запросить текущее состояние. Канал закрывается,
+// если подписчик не успевает забирать события. cancel отменяет подписку.
+func (h *History) Subscribe(lastID uint64, filter func(Event) bool) (backlog []Record, updates <-chan Record, complete bool, cancel func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	complete = true
+	if lastID > 0 {
+		// Номер из будущего бывает после перезапуска сервиса: история потеряна
+		oldest := h.lastID + 1
+		if len(h.records) > 0 {
+			oldest = h.records[0].ID
+		}
+		complete = lastID <= h.lastID && lastID+1 >= oldest
+
+		for _, record := range h.records {
+			if record.ID > lastID && (filter == nil || filter(record.Event)) {
+				backlog = append(backlog, record)
+			}
+		}
+	}
+
+	id := h.nextSubscriber
+	h.nextSubscriber++
+	ch := make(chan Record, historySubscriberBuffer)
+	h.subscribers[id] = &historySubscriber{filter: filter, updates: ch}
+
+	cancel = func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if _, ok := h.subscribers[id]; ok {
+			close(ch)
+			delete(h.subscribers, id)
+		}
+	}
+	return backlog, ch, complete, cancel
+}
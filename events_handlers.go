@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"take-a-pill/events"
+	"take-a-pill/logging"
+	"take-a-pill/problem"
+)
+
+// Событие, после которого клиенту нужно заново запросить ближайшие приемы:
+// часть событий после Last-Event-ID уже не хранится
+const eventReset = "reset"
+
+// Через сколько миллисекунд браузеру переподключаться после обрыва потока
+const eventRetryMillis = 5000
+
+// streamedEvent проверяет, отправляется ли событие в поток веб-клиентам
+func streamedEvent(eventType string) bool {
+	switch eventType {
+	case events.TypeDoseDue, events.TypeDoseTaken, events.TypeScheduleCreated, events.TypeScheduleUpdated:
+		return true
+	}
+	return false
+}
+
+// Обработчик потока событий пользователя в формате Server-Sent Events.
+// Поддерживает продолжение с заголовка Last-Event-ID.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.streamUser(w, r)
+	if !ok {
+		return
+	}
+
+	var lastID uint64
+	if value := r.Header.Get("Last-Event-ID"); value != "" {
+		var err error
+		lastID, err = strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			s.writeProblem(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "Last-Event-ID")
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		logging.FromContext(r.Context()).Error("поток событий не поддерживается", "writer", fmt.Sprintf("%T", w))
+		s.writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal)
+		return
+	}
+
+	backlog, updates, complete, cancel := s.eventHistory.Subscribe(lastID, func(e events.Event) bool {
+		return e.UserID == userID && streamedEvent(e.Type)
+	})
+	defer cancel()
+
+	// Поток событий длится дольше, чем разрешено отправлять обычный ответ
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		logging.FromContext(r.Context()).Debug("не удалось снять ограничение времени ответа", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", eventRetryMillis)
+	if !complete {
+		fmt.Fprintf(w, "event: %s\ndata: {}\n\n", eventReset)
+	}
+	for _, record := range backlog {
+		writeEvent(w, record)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(s.cfg.EventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			// Сервис останавливается, клиент переподключится с Last-Event-ID
+			return
+		case record, ok := <-updates:
+			if !ok {
+				// Клиент не успевает читать события, он переподключится с Last-Event-ID
+				return
+			}
+			writeEvent(w, record)
+			flusher.Flush()
+		case <-heartbeat.C:
+			// Комментарий не дает прокси закрыть неактивное соединение
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// streamUser определяет, чьи события получит клиент. Пользователь берется
+// из ключа API в заголовке X-API-Key, а user_id в запросе, если указан,
+// должен с ним совпадать. С ключом администратора можно открыть поток
+// любого пользователя из user_id. При ошибке отправляет ответ и возвращает false.
+func (s *Server) streamUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	requested := r.URL.Query().Get("user_id")
+	if s.isAdmin(r) {
+		if requested == "" {
+			s.writeMissingParameter(w, r, "user_id")
+			return "", false
+		}
+		return requested, true
+	}
+
+	userID, ok := s.apiKeyUser(r.Header.Get(headerAPIKey))
+	if !ok {
+		s.writeProblem(w, r, http.StatusUnauthorized, problem.CodeAPIKeyRequired)
+		return "", false
+	}
+	if requested != "" && requested != userID {
+		s.writeProblem(w, r, http.StatusForbidden, problem.CodeForeignUser)
+		return "", false
+	}
+	return userID, true
+}
+
+// writeEvent отправляет событие в формате Server-Sent Events
+func writeEvent(w http.ResponseWriter, record events.Record) {
+	data, err := json.Marshal(record.Event)
+	if err != nil {
+		slog.Warn("ошибка при отправке события", "event_id", record.ID, "error", err)
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", record.ID, record.Type, data)
+}
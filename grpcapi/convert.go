@@ -0,0 +1,114 @@
+package grpcapi
+
+import (
+	"take-a-pill/models"
+	"take-a-pill/pillpb"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Преобразования между моделями хранилища и сообщениями gRPC API
+
+func toTakingTime(t models.TakingTime) *pillpb.TakingTime {
+	return &pillpb.TakingTime{Hour: int32(t.Hour), Minute: int32(t.Minute)}
+}
+
+func toInventory(inv *models.Inventory) *pillpb.Inventory {
+	if inv == nil {
+		return nil
+	}
+	return &pillpb.Inventory{
+		PillsOnHand:  int32(inv.PillsOnHand),
+		PackSize:     int32(inv.PackSize),
+		PillsPerDose: int32(inv.PillsPerDose),
+	}
+}
+
+func fromInventory(inv *pillpb.Inventory) *models.Inventory {
+	if inv == nil {
+		return nil
+	}
+	return &models.Inventory{
+		PillsOnHand:  int(inv.PillsOnHand),
+		PackSize:     int(inv.PackSize),
+		PillsPerDose: int(inv.PillsPerDose),
+	}
+}
+
+func toMealDoses(doses []models.MealDose) []*pillpb.MealDose {
+	if len(doses) == 0 {
+		return nil
+	}
+	result := make([]*pillpb.MealDose, 0, len(doses))
+	for _, d := range doses {
+		result = append(result, &pillpb.MealDose{
+			Meal:          d.Meal,
+			Relation:      d.Relation,
+			OffsetMinutes: int32(d.OffsetMinutes),
+		})
+	}
+	return result
+}
+
+func fromMealDoses(doses []*pillpb.MealDose) []models.MealDose {
+	if len(doses) == 0 {
+		return nil
+	}
+	result := make([]models.MealDose, 0, len(doses))
+	for _, d := range doses {
+		result = append(result, models.MealDose{
+			Meal:          d.GetMeal(),
+			Relation:      d.GetRelation(),
+			OffsetMinutes: int(d.GetOffsetMinutes()),
+		})
+	}
+	return result
+}
+
+func toSchedule(s *models.Schedule) *pillpb.Schedule {
+	takingTimes := make([]*pillpb.TakingTime, 0, len(s.TakingTimes))
+	for _, t := range s.TakingTimes {
+		takingTimes = append(takingTimes, toTakingTime(t))
+	}
+
+	return &pillpb.Schedule{
+		Id:             s.ID,
+		UserId:         s.UserID,
+		MedicineName:   s.MedicineName,
+		Frequency:      int32(s.Frequency),
+		Duration:       int32(s.Duration),
+		CreatedAt:      timestamppb.New(s.CreatedAt),
+		TakingTimes:    takingTimes,
+		Distribution:   s.Distribution,
+		MealDoses:      toMealDoses(s.MealDoses),
+		Inventory:      toInventory(s.Inventory),
+		PrescriptionId: s.PrescriptionID,
+		MedicineId:     s.MedicineID,
+		Paused:         s.Paused,
+		Version:        int32(s.Version),
+	}
+}
+
+func toNextTaking(t models.NextTaking) *pillpb.NextTaking {
+	return &pillpb.NextTaking{
+		ScheduleId:     t.ScheduleID,
+		MedicineName:   t.MedicineName,
+		NextTakingTime: toTakingTime(t.NextTakingTime),
+	}
+}
+
+func toNextTakings(takings []models.NextTaking) []*pillpb.NextTaking {
+	result := make([]*pillpb.NextTaking, 0, len(takings))
+	for _, t := range takings {
+		result = append(result, toNextTaking(t))
+	}
+	return result
+}
+
+func toWarnings(warnings []models.Warning) []*pillpb.Warning {
+	result := make([]*pillpb.Warning, 0, len(warnings))
+	for _, w := range warnings {
+		result = append(result, &pillpb.Warning{Code: w.Code, Message: w.Message, Severity: w.Severity})
+	}
+	return result
+}
@@ -0,0 +1,272 @@
+// Package grpcapi реализует gRPC API сервиса поверх того же хранилища, что и REST
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"take-a-pill/events"
+	"take-a-pill/i18n"
+	"take-a-pill/interactions"
+	"take-a-pill/models"
+	"take-a-pill/pillpb"
+	"take-a-pill/storage"
+	"take-a-pill/validation"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Сколько изменений может ждать отправки в потоке WatchNextTakings.
+// Если клиент не успевает их читать, лишние изменения отбрасываются:
+// каждое сообщение содержит полный список приемов, поэтому клиент
+// все равно получит актуальное состояние со следующим сообщением.
+const watchBuffer = 16
+
+// Server реализует pillpb.ScheduleServiceServer
+type Server struct {
+	pillpb.UnimplementedScheduleServiceServer
+	db *storage.MemoryStorage
+	// Логгер сервиса, который скрывает ID пользователей и медицинские данные
+	logger *slog.Logger
+	// Закрывается при остановке сервиса, чтобы завершить потоки
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewServer создает gRPC сервис поверх хранилища. Записи пишутся
+// в logger, а не в логгер по умолчанию, который может не скрывать данные.
+func NewServer(db *storage.MemoryStorage, logger *slog.Logger) *Server {
+	return &Server{db: db, logger: logger, done: make(chan struct{})}
+}
+
+// Close завершает открытые потоки WatchNextTakings, чтобы сервер
+// мог остановиться, не дожидаясь отключения клиентов
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// Register регистрирует сервис на gRPC сервере
+func (s *Server) Register(g *grpc.Server) {
+	pillpb.RegisterScheduleServiceServer(g, s)
+}
+
+// CreateSchedule создает расписание
+func (s *Server) CreateSchedule(ctx context.Context, req *pillpb.CreateScheduleRequest) (*pillpb.CreateScheduleResponse, error) {
+	schedule, warnings, err := s.db.CreateSchedule(ctx, &models.ScheduleRequest{
+		UserID:         req.GetUserId(),
+		MedicineName:   req.GetMedicineName(),
+		Frequency:      int(req.GetFrequency()),
+		Duration:       int(req.GetDuration()),
+		Distribution:   req.GetDistribution(),
+		MealDoses:      fromMealDoses(req.GetMealDoses()),
+		Inventory:      fromInventory(req.GetInventory()),
+		PrescriptionID: req.GetPrescriptionId(),
+		MedicineID:     req.GetMedicineId(),
+	})
+	if err != nil {
+		return nil, s.statusError(ctx, err)
+	}
+
+	lang := language(ctx)
+	for i := range warnings {
+		warnings[i].Message = i18n.T(lang, warnings[i].Code, warnings[i].Args...)
+	}
+
+	return &pillpb.CreateScheduleResponse{
+		Schedule: toSchedule(schedule),
+		Warnings: toWarnings(warnings),
+	}, nil
+}
+
+// GetSchedule возвращает расписание пользователя
+func (s *Server) GetSchedule(ctx context.Context, req *pillpb.GetScheduleRequest) (*pillpb.Schedule, error) {
+	if err := requireUser(ctx, req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.db.GetScheduleByID(ctx, req.GetScheduleId())
+	if err != nil || schedule.UserID != req.GetUserId() {
+		return nil, status.Error(codes.NotFound, i18n.T(language(ctx), storage.CodeScheduleNotFound))
+	}
+
+	return toSchedule(schedule), nil
+}
+
+// ListSchedules возвращает страницу расписаний пользователя
+func (s *Server) ListSchedules(ctx context.Context, req *pillpb.ListSchedulesRequest) (*pillpb.ListSchedulesResponse, error) {
+	if err := requireUser(ctx, req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	schedules, next, err := s.db.ListSchedules(ctx, req.GetUserId(), storage.ListOptions{
+		Status:   req.GetStatus(),
+		Medicine: req.GetMedicine(),
+		Sort:     req.GetSort(),
+		Limit:    int(req.GetLimit()),
+		Cursor:   req.GetCursor(),
+	})
+	if err != nil {
+		return nil, s.statusError(ctx, err)
+	}
+
+	response := &pillpb.ListSchedulesResponse{NextCursor: next}
+	for _, schedule := range schedules {
+		response.Schedules = append(response.Schedules, toSchedule(schedule))
+	}
+	return response, nil
+}
+
+// GetNextTakings возвращает ближайшие приемы пользователя
+func (s *Server) GetNextTakings(ctx context.Context, req *pillpb.GetNextTakingsRequest) (*pillpb.GetNextTakingsResponse, error) {
+	if err := requireUser(ctx, req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	return &pillpb.GetNextTakingsResponse{
+		Takings: toNextTakings(s.db.GetNextTakings(ctx, req.GetUserId())),
+	}, nil
+}
+
+// WatchNextTakings отправляет текущий список ближайших приемов, а затем
+// новый список при каждом событии пользователя, влияющем на приемы
+func (s *Server) WatchNextTakings(req *pillpb.WatchNextTakingsRequest, stream pillpb.ScheduleService_WatchNextTakingsServer) error {
+	ctx := stream.Context()
+	userID := req.GetUserId()
+	if err := requireUser(ctx, userID); err != nil {
+		return err
+	}
+
+	updates := make(chan events.Event, watchBuffer)
+	unsubscribe := s.db.Events().Subscribe(func(e events.Event) {
+		if e.UserID != userID || !affectsNextTakings(e.Type) {
+			return
+		}
+		select {
+		case updates <- e:
+		default:
+			s.logger.Warn("поток ближайших приемов не успевает, событие пропущено", "user_id", userID, "type", e.Type)
+		}
+	})
+	defer unsubscribe()
+
+	snapshot := &pillpb.NextTakingsUpdate{
+		Reason:  "snapshot",
+		Time:    timestamppb.Now(),
+		Takings: toNextTakings(s.db.GetNextTakings(ctx, userID)),
+	}
+	if err := stream.Send(snapshot); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.done:
+			return status.Error(codes.Unavailable, i18n.T(language(ctx), "shutting_down"))
+		case e := <-updates:
+			if err := stream.Send(s.update(ctx, e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// update готовит сообщение об изменении ближайших приемов по событию
+func (s *Server) update(ctx context.Context, e events.Event) *pillpb.NextTakingsUpdate {
+	update := &pillpb.NextTakingsUpdate{
+		Reason:     e.Type,
+		Time:       timestamppb.New(e.Time),
+		ScheduleId: e.ScheduleID,
+		Takings:    toNextTakings(s.db.GetNextTakings(ctx, e.UserID)),
+	}
+
+	if e.Type == events.TypeDoseDue {
+		medicine, _ := e.Data["medicine_name"].(string)
+		hour, _ := e.Data["hour"].(int)
+		minute, _ := e.Data["minute"].(int)
+		update.Due = toNextTaking(models.NextTaking{
+			ScheduleID:     e.ScheduleID,
+			MedicineName:   medicine,
+			NextTakingTime: models.TakingTime{Hour: hour, Minute: minute},
+		})
+	}
+	return update
+}
+
+// affectsNextTakings проверяет, меняет ли событие список ближайших приемов
+func affectsNextTakings(eventType string) bool {
+	switch eventType {
+	case events.TypeDoseDue, events.TypeDoseTaken, events.TypeScheduleCreated, events.TypeScheduleUpdated:
+		return true
+	}
+	return false
+}
+
+// requireUser проверяет, что в запросе указан пользователь
+func requireUser(ctx context.Context, userID string) error {
+	if userID == "" {
+		return status.Error(codes.InvalidArgument, i18n.T(language(ctx), "missing_parameter", "user_id"))
+	}
+	return nil
+}
+
+// language выбирает язык сообщений из метаданных accept-language
+func language(ctx context.Context) i18n.Lang {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, value := range md.Get("accept-language") {
+			if lang, ok := i18n.ParseAcceptLanguage(value); ok {
+				return lang
+			}
+		}
+	}
+	return i18n.Default
+}
+
+// localizer реализуется ошибками, текст которых можно получить на другом языке
+type localizer interface {
+	Localize(lang i18n.Lang) string
+}
+
+// statusError выбирает код gRPC по типу ошибки хранилища
+func (s *Server) statusError(ctx context.Context, err error) error {
+	code := codes.Internal
+	var fieldErrors validation.Errors
+	var contraindication *interactions.ContraindicationError
+	var doseLimit *storage.DoseLimitError
+	switch {
+	case errors.As(err, &fieldErrors), errors.Is(err, storage.ErrInvalid):
+		code = codes.InvalidArgument
+	case errors.As(err, &contraindication), errors.As(err, &doseLimit), errors.Is(err, storage.ErrConflict):
+		code = codes.FailedPrecondition
+	case errors.Is(err, storage.ErrPreconditionFailed):
+		code = codes.Aborted
+	case errors.Is(err, storage.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, storage.ErrUnavailable):
+		code = codes.Unavailable
+	}
+
+	if code == codes.Internal {
+		s.logger.Error("внутренняя ошибка gRPC", "error", err)
+		return status.Error(code, i18n.T(language(ctx), "internal_error"))
+	}
+
+	message := err.Error()
+	var l localizer
+	switch {
+	case fieldErrors != nil:
+		message = fieldErrors.Localize(language(ctx)).Error()
+	case errors.As(err, &l):
+		message = l.Localize(language(ctx))
+	}
+	return status.Error(code, message)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"take-a-pill/problem"
+)
+
+// Сколько ждать ответа хранилища при проверке готовности
+const readinessTimeout = 2 * time.Second
+
+// Обработчик проверки, что процесс жив. Не зависит от хранилища,
+// чтобы оркестратор не перезапускал сервис из-за временных проблем.
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// Обработчик проверки готовности принимать запросы: сервис не останавливается
+// и хранилище отвечает
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	if s.draining.Load() {
+		s.writeProblem(w, r, http.StatusServiceUnavailable, problem.CodeShuttingDown)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+	if err := s.db.Ping(ctx); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+}
@@ -0,0 +1,87 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lang - код языка сообщений
+type Lang string
+
+// Поддерживаемые языки
+const (
+	Russian Lang = "ru"
+	English Lang = "en"
+)
+
+// Default - язык сообщений по умолчанию
+const Default = Russian
+
+// Supported проверяет, есть ли сообщения на заданном языке
+func Supported(lang Lang) bool {
+	return lang == Russian || lang == English
+}
+
+// T возвращает сообщение по ключу на заданном языке, подставляя аргументы.
+// Если перевода нет, используется язык по умолчанию, а если нет и его - сам ключ.
+func T(lang Lang, key string, args ...any) string {
+	translations, ok := messages[key]
+	if !ok {
+		return key
+	}
+
+	format, ok := translations[lang]
+	if !ok {
+		format = translations[Default]
+	}
+
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// ParseAcceptLanguage выбирает поддерживаемый язык из заголовка Accept-Language
+// с учетом весов q. Если подходящего языка нет, возвращает false.
+func ParseAcceptLanguage(header string) (Lang, bool) {
+	type option struct {
+		lang Lang
+		q    float64
+	}
+
+	var options []option
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if value, ok := strings.CutPrefix(param, "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		// Берем только основной язык: "en-US" -> "en"
+		lang := Lang(strings.SplitN(tag, "-", 2)[0])
+		if q > 0 && Supported(lang) {
+			options = append(options, option{lang: lang, q: q})
+		}
+	}
+
+	if len(options) == 0 {
+		return "", false
+	}
+
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].q > options[j].q
+	})
+	return options[0].lang, true
+}
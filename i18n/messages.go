@@ -0,0 +1,299 @@
+package i18n
+
+// messages содержит тексты сообщений по ключу и языку
+var messages = map[string]map[Lang]string{
+	// Ошибки валидации полей
+	"validation.request.required": {
+		Russian: "запрос не может быть пустым",
+		English: "request must not be empty",
+	},
+	"validation.user_id.required": {
+		Russian: "не указан идентификатор пользователя",
+		English: "user ID is required",
+	},
+	"validation.medicine_name.required": {
+		Russian: "не указано название лекарства",
+		English: "medicine name is required",
+	},
+	"validation.frequency.out_of_range": {
+		Russian: "частота приема должна быть от 1 до 24 раз в день",
+		English: "frequency must be between 1 and 24 times a day",
+	},
+	"validation.frequency.too_frequent": {
+		Russian: "столько приемов в день не помещается в дневное время с заданным шагом",
+		English: "this many doses a day do not fit into the daytime window at the configured step",
+	},
+	"validation.distribution.invalid": {
+		Russian: "способ распределения приемов должен быть even, front_loaded или meal_anchored",
+		English: "distribution must be even, front_loaded or meal_anchored",
+	},
+	"validation.frequency.invalid": {
+		Russian: "частота приема должна совпадать с числом приемов относительно еды",
+		English: "frequency must match the number of meal doses",
+	},
+	"validation.meal.invalid": {
+		Russian: "прием пищи должен быть breakfast, lunch или dinner",
+		English: "meal must be breakfast, lunch or dinner",
+	},
+	"validation.relation.invalid": {
+		Russian: "прием относительно еды должен быть before, with или after",
+		English: "relation to meal must be before, with or after",
+	},
+	"validation.offset_minutes.out_of_range": {
+		Russian: "промежуток между приемом лекарства и едой должен быть от 0 до 240 минут",
+		English: "offset from meal must be between 0 and 240 minutes",
+	},
+	"validation.offset_minutes.invalid": {
+		Russian: "для приема во время еды промежуток не указывается",
+		English: "offset must not be set for a dose taken with a meal",
+	},
+	"validation.meal_doses.invalid": {
+		Russian: "приемы относительно еды должны приходиться на разное время в пределах суток",
+		English: "meal doses must fall on different times within the same day",
+	},
+	"validation.duration.negative": {
+		Russian: "продолжительность лечения не может быть отрицательной",
+		English: "duration must not be negative",
+	},
+	"validation.pills_on_hand.negative": {
+		Russian: "количество таблеток не может быть отрицательным",
+		English: "pills on hand must not be negative",
+	},
+	"validation.pack_size.negative": {
+		Russian: "размер упаковки не может быть отрицательным",
+		English: "pack size must not be negative",
+	},
+	"validation.pills_per_dose.negative": {
+		Russian: "количество таблеток за прием не может быть отрицательным",
+		English: "pills per dose must not be negative",
+	},
+	"validation.prescriber.required": {
+		Russian: "не указан врач, выписавший рецепт",
+		English: "prescriber is required",
+	},
+	"validation.issue_date.required": {
+		Russian: "не указана дата выписки рецепта",
+		English: "issue date is required",
+	},
+	"validation.valid_until.required": {
+		Russian: "не указан срок действия рецепта",
+		English: "expiry date is required",
+	},
+	"validation.valid_until.invalid": {
+		Russian: "срок действия рецепта должен заканчиваться после даты выписки",
+		English: "prescription must expire after its issue date",
+	},
+	"validation.refills.negative": {
+		Russian: "количество повторных выдач не может быть отрицательным",
+		English: "refills must not be negative",
+	},
+	"validation.language.invalid": {
+		Russian: "язык не поддерживается",
+		English: "language is not supported",
+	},
+	"validation.meals.invalid": {
+		Russian: "время еды указывается для breakfast, lunch и dinner в пределах суток",
+		English: "meal times must be given for breakfast, lunch and dinner within a day",
+	},
+
+	// Ошибки хранилища
+	"schedule_not_found": {
+		Russian: "расписание не найдено",
+		English: "schedule not found",
+	},
+	"profile_not_found": {
+		Russian: "настройки пользователя не найдены",
+		English: "user profile not found",
+	},
+	"prescription_not_found": {
+		Russian: "рецепт не найден",
+		English: "prescription not found",
+	},
+	"prescription_expired": {
+		Russian: "срок действия рецепта истек",
+		English: "prescription has expired",
+	},
+	"prescription_mismatch": {
+		Russian: "лекарство не совпадает с указанным в рецепте",
+		English: "medicine does not match the prescription",
+	},
+	"no_refills_left": {
+		Russian: "по рецепту не осталось повторных выдач",
+		English: "no refills left on the prescription",
+	},
+	"inventory_not_set": {
+		Russian: "запас таблеток не задан",
+		English: "inventory is not set",
+	},
+	"pack_size_not_set": {
+		Russian: "не указан размер упаковки",
+		English: "pack size is not set",
+	},
+	"insufficient_stock": {
+		Russian: "недостаточно таблеток для приема",
+		English: "not enough pills for a dose",
+	},
+	"invalid_packs": {
+		Russian: "количество упаковок не может быть отрицательным",
+		English: "number of packs must not be negative",
+	},
+	"medicine_not_found": {
+		Russian: "лекарство не найдено в справочнике",
+		English: "medicine not found in the catalog",
+	},
+	"catalog_unavailable": {
+		Russian: "справочник лекарств недоступен",
+		English: "medicine catalog is unavailable",
+	},
+	"storage_unavailable": {
+		Russian: "хранилище расписаний не отвечает",
+		English: "schedule storage is not responding",
+	},
+	"interactions_unavailable": {
+		Russian: "проверка взаимодействия лекарств отключена",
+		English: "drug interaction checking is disabled",
+	},
+	"contraindicated": {
+		Russian: "противопоказан совместный прием: %s",
+		English: "contraindicated combination: %s",
+	},
+	"contraindicated.pair": {
+		Russian: "%s и %s",
+		English: "%s and %s",
+	},
+	"daily_dose_limit": {
+		Russian: "суточная доза вещества «%s» %g %s превышает максимум %g %s",
+		English: "daily amount of %q %g %s exceeds the maximum of %g %s",
+	},
+	"dose_limit_unchecked": {
+		Russian: "не удалось проверить суточную дозу «%s»",
+		English: "could not check the daily dose of %q",
+	},
+	"schedule_quota_exceeded": {
+		Russian: "у пользователя уже %d действующих расписаний, это максимум",
+		English: "user already has %d active schedules, which is the maximum",
+	},
+	"separation_conflict": {
+		Russian: "не удалось разнести приемы «%s» и «%s» хотя бы на %d мин в пределах дня",
+		English: "cannot keep doses of %s and %s at least %d minutes apart within the day",
+	},
+	"reminders_changed": {
+		Russian: "курсы пользователя изменились после просмотра, запросите объединение напоминаний заново",
+		English: "the user's schedules have changed since the preview, request the consolidation again",
+	},
+	"version_mismatch": {
+		Russian: "расписание уже изменено, актуальная версия: %d",
+		English: "schedule has been modified, current version: %d",
+	},
+	"profile_changed": {
+		Russian: "настройки пользователя уже изменены, актуальная версия: %d",
+		English: "user settings have been modified, current version: %d",
+	},
+
+	// Предупреждения
+	"course_outlives_prescription": {
+		Russian: "курс продлится дольше, чем действует рецепт (до %s)",
+		English: "the course outlasts the prescription (valid until %s)",
+	},
+	"drug_interaction": {
+		Russian: "%s и %s: %s",
+		English: "%s and %s: %s",
+	},
+	"doses_shifted": {
+		Russian: "время приема «%s» сдвинуто на %+d мин, чтобы разнести его с другими лекарствами",
+		English: "doses of %s moved by %+d min to keep them apart from other medicines",
+	},
+
+	// Общие ошибки API
+	"invalid_json": {
+		Russian: "Ошибка при чтении данных",
+		English: "Failed to read request data",
+	},
+	"missing_parameter": {
+		Russian: "не указан %s",
+		English: "%s is required",
+	},
+	"invalid_parameter": {
+		Russian: "некорректный %s",
+		English: "invalid %s",
+	},
+	"validation_failed": {
+		Russian: "запрос содержит ошибки",
+		English: "request contains errors",
+	},
+	"not_found": {
+		Russian: "страница не найдена",
+		English: "page not found",
+	},
+	"method_not_allowed": {
+		Russian: "метод не поддерживается",
+		English: "method not allowed",
+	},
+	"internal_error": {
+		Russian: "внутренняя ошибка сервера",
+		English: "internal server error",
+	},
+
+	"invalid_request": {
+		Russian: "запрос не соответствует спецификации API: %s",
+		English: "request does not match the API specification: %s",
+	},
+	"invalid_response": {
+		Russian: "ответ не соответствует спецификации API: %s",
+		English: "response does not match the API specification: %s",
+	},
+	"precondition_required": {
+		Russian: "для изменения расписания укажите его ETag в заголовке If-Match",
+		English: "send the schedule ETag in the If-Match header to modify it",
+	},
+	"idempotency_key_reused": {
+		Russian: "ключ идемпотентности уже использован с другими данными запроса",
+		English: "idempotency key was already used with a different request body",
+	},
+	"idempotency_in_progress": {
+		Russian: "запрос с этим ключом идемпотентности еще выполняется",
+		English: "a request with this idempotency key is still in progress",
+	},
+	"rate_limited": {
+		Russian: "слишком много запросов, повторите позже",
+		English: "too many requests, try again later",
+	},
+	"request_too_large": {
+		Russian: "тело запроса больше %d байт",
+		English: "request body is larger than %d bytes",
+	},
+	"admin_only": {
+		Russian: "параметр %s доступен только администратору",
+		English: "parameter %s is available to administrators only",
+	},
+	"admin_required": {
+		Russian: "операция доступна только администратору",
+		English: "this operation is available to administrators only",
+	},
+	"api_key_required": {
+		Russian: "нужен ключ API пользователя в заголовке X-API-Key",
+		English: "a user API key is required in the X-API-Key header",
+	},
+	"foreign_user": {
+		Russian: "ключ API не дает доступа к данным этого пользователя",
+		English: "the API key does not grant access to this user's data",
+	},
+	"shutting_down": {
+		Russian: "сервис останавливается",
+		English: "service is shutting down",
+	},
+
+	// Тексты уведомлений
+	"notification.inventory.low_stock": {
+		Russian: "Таблетки «%s» скоро закончатся: осталось %d шт., хватит на %d дн. (до %s). Пора пополнить запас.",
+		English: "You are running low on %s: %d pills left, enough for %d days (until %s). Time to refill.",
+	},
+	"notification.dose.due": {
+		Russian: "Пора принять «%s» (%02d:%02d).",
+		English: "Time to take %s (%02d:%02d).",
+	},
+	"notification.dose.taken": {
+		Russian: "Прием «%s» отмечен.",
+		English: "%s dose logged.",
+	},
+}
@@ -0,0 +1,125 @@
+package idempotency
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"take-a-pill/clock"
+)
+
+// State описывает, как обработать запрос с ключом идемпотентности
+type State int
+
+const (
+	// Ключ встречается впервые, запрос нужно выполнить
+	StateNew State = iota
+	// Запрос с этим ключом уже выполнен, нужно вернуть сохраненный ответ
+	StateReplay
+	// Ключ уже использован с другим телом запроса
+	StateMismatch
+	// Запрос с этим ключом еще выполняется
+	StateInProgress
+)
+
+// Response - сохраненный ответ на запрос
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// entry - запись о запросе с ключом идемпотентности
+type entry struct {
+	// Отпечаток тела запроса
+	fingerprint string
+	// Сохраненный ответ (nil, пока запрос выполняется)
+	response *Response
+	// Когда запись можно удалить
+	expiresAt time.Time
+}
+
+// Store хранит ответы на запросы по ключам идемпотентности в памяти
+type Store struct {
+	// Записи по ключу
+	entries map[string]*entry
+	// Сколько хранить ответ
+	ttl time.Duration
+	// Часы, по которым устаревают записи
+	clock clock.Clock
+	// Когда последний раз удаляли устаревшие записи
+	lastSweep time.Time
+	// Мьютекс для безопасной работы с картой
+	mu sync.Mutex
+}
+
+// NewStore создает хранилище ответов, которые хранятся ttl по заданным часам
+func NewStore(ttl time.Duration, c clock.Clock) *Store {
+	return &Store{
+		entries: make(map[string]*entry),
+		ttl:     ttl,
+		clock:   c,
+	}
+}
+
+// Begin регистрирует запрос с ключом и отпечатком тела. Для StateReplay
+// возвращает сохраненный ответ. Для StateNew вызывающий обязан затем вызвать
+// Complete или Abort.
+func (s *Store) Begin(key, fingerprint string) (State, *Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	s.sweep(now)
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expiresAt) {
+		switch {
+		case e.fingerprint != fingerprint:
+			return StateMismatch, nil
+		case e.response == nil:
+			return StateInProgress, nil
+		default:
+			return StateReplay, e.response
+		}
+	}
+
+	s.entries[key] = &entry{
+		fingerprint: fingerprint,
+		expiresAt:   now.Add(s.ttl),
+	}
+	return StateNew, nil
+}
+
+// Complete сохраняет ответ на запрос с ключом
+func (s *Store) Complete(key string, response Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.response = &response
+		e.expiresAt = s.clock.Now().Add(s.ttl)
+	}
+}
+
+// Abort удаляет ключ, чтобы запрос можно было повторить
+func (s *Store) Abort(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+}
+
+// sweep удаляет устаревшие записи не чаще раза в минуту.
+// Вызывается под блокировкой.
+func (s *Store) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
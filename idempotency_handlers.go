@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"take-a-pill/idempotency"
+	"take-a-pill/problem"
+)
+
+// Заголовки для идемпотентных запросов
+const (
+	headerIdempotencyKey = "Idempotency-Key"
+	headerReplayed       = "Idempotent-Replayed"
+)
+
+// Заголовки, которые относятся к конкретному запросу. При повторе они
+// не восстанавливаются из сохраненного ответа: X-Request-ID повтора
+// должен совпадать с его собственным, иначе логи двух запросов перепутаются.
+var perRequestHeaders = []string{headerRequestID, "Date"}
+
+// Максимальная длина ключа идемпотентности
+const maxIdempotencyKeyLength = 255
+
+// Коды ошибок идемпотентных запросов
+const (
+	codeIdempotencyKeyReused  = "idempotency_key_reused"
+	codeIdempotencyInProgress = "idempotency_in_progress"
+)
+
+// responseRecorder запоминает статус и тело ответа, передавая их дальше клиенту
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(data []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(data)
+	return rec.ResponseWriter.Write(data)
+}
+
+// idempotent позволяет безопасно повторять запрос с заголовком Idempotency-Key:
+// повтор с тем же телом получает сохраненный ответ, а повтор с другим телом - 422.
+// Ключи разных клиентов, пользователей и маршрутов не пересекаются.
+func (s *Server) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(headerIdempotencyKey)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			s.writeProblem(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, headerIdempotencyKey)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			s.writeInvalidJSON(w, r, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		key = idempotencyScope(r, s.clientKey(r), body) + "|" + key
+		sum := sha256.Sum256(body)
+		state, saved := s.idempotency.Begin(key, hex.EncodeToString(sum[:]))
+
+		switch state {
+		case idempotency.StateReplay:
+			for name, values := range saved.Header {
+				w.Header()[name] = values
+			}
+			w.Header().Set(headerReplayed, "true")
+			w.WriteHeader(saved.Status)
+			w.Write(saved.Body)
+			return
+		case idempotency.StateMismatch:
+			s.writeProblem(w, r, http.StatusUnprocessableEntity, codeIdempotencyKeyReused)
+			return
+		case idempotency.StateInProgress:
+			s.writeProblem(w, r, http.StatusConflict, codeIdempotencyInProgress)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		next(rec, r)
+
+		// Ошибки сервера не сохраняем, чтобы запрос можно было повторить
+		if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+			s.idempotency.Abort(key)
+			return
+		}
+		header := w.Header().Clone()
+		for _, name := range perRequestHeaders {
+			header.Del(name)
+		}
+		s.idempotency.Complete(key, idempotency.Response{
+			Status: rec.status,
+			Header: header,
+			Body:   rec.body.Bytes(),
+		})
+	}
+}
+
+// idempotencyScope возвращает пространство ключей идемпотентности запроса:
+// маршрут, клиент и пользователь из тела. Так одинаковый ключ у разных
+// клиентов не вернет одному из них ответ, сохраненный для другого.
+func idempotencyScope(r *http.Request, client string, body []byte) string {
+	var request struct {
+		UserID string `json:"user_id"`
+	}
+	// Ошибку в теле вернет сам обработчик
+	json.Unmarshal(body, &request)
+	return rateLimitRoute(r) + "|" + client + "|user:" + request.UserID
+}
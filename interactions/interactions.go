@@ -0,0 +1,311 @@
+package interactions
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"take-a-pill/i18n"
+)
+
+// Severity описывает тяжесть взаимодействия лекарств
+type Severity string
+
+// Уровни тяжести взаимодействия, от легкого к самому опасному
+const (
+	SeverityMinor           Severity = "minor"
+	SeverityModerate        Severity = "moderate"
+	SeverityMajor           Severity = "major"
+	SeverityContraindicated Severity = "contraindicated"
+)
+
+// rank возвращает порядковый номер уровня тяжести для сортировки
+func (s Severity) rank() int {
+	switch s {
+	case SeverityMinor:
+		return 1
+	case SeverityModerate:
+		return 2
+	case SeverityMajor:
+		return 3
+	case SeverityContraindicated:
+		return 4
+	}
+	return 0
+}
+
+// Interaction описывает взаимодействие двух действующих веществ
+type Interaction struct {
+	// Первое действующее вещество
+	A string `json:"a"`
+	// Второе действующее вещество
+	B string `json:"b"`
+	// Тяжесть взаимодействия
+	Severity Severity `json:"severity"`
+	// Описание взаимодействия
+	Description string `json:"description"`
+	// На сколько минут нужно разнести приемы веществ (0 - можно принимать вместе)
+	SeparationMinutes int `json:"separation_minutes,omitempty"`
+}
+
+// Medicine описывает лекарство для проверки взаимодействий
+type Medicine struct {
+	// Название лекарства
+	Name string
+	// Действующие вещества. Если не указаны, определяются по названию.
+	Ingredients []string
+}
+
+// Finding описывает найденное взаимодействие между двумя лекарствами
+type Finding struct {
+	// Лекарство, которое добавляет пользователь
+	Medicine string `json:"medicine"`
+	// Уже назначенное лекарство
+	Other string `json:"other"`
+	Interaction
+}
+
+// ContraindicationError возвращается, когда лекарства нельзя принимать вместе
+type ContraindicationError struct {
+	Findings []Finding
+}
+
+func (e *ContraindicationError) Error() string {
+	return e.Localize(i18n.Default)
+}
+
+// Localize возвращает текст ошибки на заданном языке
+func (e *ContraindicationError) Localize(lang i18n.Lang) string {
+	var pairs []string
+	for _, f := range e.Findings {
+		pairs = append(pairs, i18n.T(lang, "contraindicated.pair", f.Medicine, f.Other))
+	}
+	return i18n.T(lang, "contraindicated", strings.Join(pairs, ", "))
+}
+
+// Формат файла с таблицей взаимодействий
+type table struct {
+	// Соответствие названий лекарств действующим веществам
+	Aliases map[string][]string `json:"aliases"`
+	// Попарные взаимодействия действующих веществ
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Checker проверяет взаимодействие лекарств по таблице из файла
+type Checker struct {
+	// Путь к файлу с таблицей
+	path string
+	// Действующие вещества по нормализованному названию лекарства
+	aliases map[string][]string
+	// Взаимодействия по паре нормализованных веществ
+	pairs map[[2]string]Interaction
+	// Мьютекс для безопасной перезагрузки таблицы
+	mu sync.RWMutex
+}
+
+// Load загружает таблицу взаимодействий из JSON или CSV файла
+func Load(path string) (*Checker, error) {
+	c := &Checker{path: path}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload перечитывает таблицу взаимодействий из файла
+func (c *Checker) Reload() error {
+	t, err := readTable(c.path)
+	if err != nil {
+		return err
+	}
+
+	aliases := make(map[string][]string)
+	for name, ingredients := range t.Aliases {
+		for _, ingredient := range ingredients {
+			aliases[Normalize(name)] = append(aliases[Normalize(name)], Normalize(ingredient))
+		}
+	}
+
+	pairs := make(map[[2]string]Interaction)
+	for _, interaction := range t.Interactions {
+		if interaction.Severity.rank() == 0 {
+			return fmt.Errorf("неизвестная тяжесть взаимодействия %q для %s и %s",
+				interaction.Severity, interaction.A, interaction.B)
+		}
+		if interaction.SeparationMinutes < 0 {
+			return fmt.Errorf("отрицательный промежуток между приемами %s и %s", interaction.A, interaction.B)
+		}
+		pairs[pairKey(Normalize(interaction.A), Normalize(interaction.B))] = interaction
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.aliases = aliases
+	c.pairs = pairs
+	return nil
+}
+
+// Ingredients возвращает действующие вещества лекарства. Если лекарство
+// неизвестно, его название считается названием действующего вещества.
+func (c *Checker) Ingredients(medicine string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.ingredients(medicine)
+}
+
+func (c *Checker) ingredients(medicine string) []string {
+	name := Normalize(medicine)
+	if ingredients, ok := c.aliases[name]; ok {
+		return ingredients
+	}
+	return []string{name}
+}
+
+// medicineIngredients возвращает нормализованные действующие вещества лекарства
+func (c *Checker) medicineIngredients(medicine Medicine) []string {
+	if len(medicine.Ingredients) == 0 {
+		return c.ingredients(medicine.Name)
+	}
+
+	ingredients := make([]string, 0, len(medicine.Ingredients))
+	for _, ingredient := range medicine.Ingredients {
+		ingredients = append(ingredients, Normalize(ingredient))
+	}
+	return ingredients
+}
+
+// Check ищет взаимодействия лекарства с уже назначенными лекарствами.
+// Результат отсортирован от самого опасного взаимодействия к легкому.
+func (c *Checker) Check(medicine Medicine, others []Medicine) []Finding {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var findings []Finding
+	for _, other := range others {
+		for _, a := range c.medicineIngredients(medicine) {
+			for _, b := range c.medicineIngredients(other) {
+				if interaction, ok := c.pairs[pairKey(a, b)]; ok {
+					findings = append(findings, Finding{
+						Medicine:    medicine.Name,
+						Other:       other.Name,
+						Interaction: interaction,
+					})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Severity.rank() > findings[j].Severity.rank()
+	})
+	return findings
+}
+
+// Separation возвращает, на сколько нужно разнести приемы двух лекарств.
+// Если разносить не нужно, возвращает 0.
+func (c *Checker) Separation(a, b Medicine) time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var separation time.Duration
+	for _, x := range c.medicineIngredients(a) {
+		for _, y := range c.medicineIngredients(b) {
+			if interaction, ok := c.pairs[pairKey(x, y)]; ok {
+				separation = max(separation, time.Duration(interaction.SeparationMinutes)*time.Minute)
+			}
+		}
+	}
+	return separation
+}
+
+// Contraindicated возвращает ошибку, если среди взаимодействий есть противопоказанные
+func Contraindicated(findings []Finding) error {
+	var contraindicated []Finding
+	for _, f := range findings {
+		if f.Severity == SeverityContraindicated {
+			contraindicated = append(contraindicated, f)
+		}
+	}
+	if len(contraindicated) == 0 {
+		return nil
+	}
+	return &ContraindicationError{Findings: contraindicated}
+}
+
+// Normalize приводит название лекарства или вещества к единому виду:
+// нижний регистр, без дозировки и лишних пробелов
+func Normalize(name string) string {
+	name = strings.ToLower(strings.ReplaceAll(name, "ё", "е"))
+
+	var words []string
+	for _, word := range strings.Fields(name) {
+		// Отбрасываем дозировку вроде "500" или "500мг"
+		if unicode.IsDigit([]rune(word)[0]) {
+			continue
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " ")
+}
+
+// pairKey возвращает ключ пары веществ, не зависящий от их порядка
+func pairKey(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// readTable читает таблицу взаимодействий. CSV файл содержит только
+// взаимодействия в колонках a, b, severity, description, separation_minutes.
+func readTable(path string) (*table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть таблицу взаимодействий: %w", err)
+	}
+	defer f.Close()
+
+	var t table
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		records, err := csv.NewReader(f).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("не удалось прочитать таблицу взаимодействий: %w", err)
+		}
+		for i, record := range records {
+			// Пропускаем заголовок
+			if i == 0 && record[0] == "a" {
+				continue
+			}
+			if len(record) < 3 {
+				return nil, fmt.Errorf("строка %d таблицы взаимодействий: ожидалось минимум 3 колонки", i+1)
+			}
+			interaction := Interaction{A: record[0], B: record[1], Severity: Severity(record[2])}
+			if len(record) > 3 {
+				interaction.Description = record[3]
+			}
+			if len(record) > 4 && record[4] != "" {
+				interaction.SeparationMinutes, err = strconv.Atoi(record[4])
+				if err != nil {
+					return nil, fmt.Errorf("строка %d таблицы взаимодействий: неверный промежуток между приемами: %w", i+1, err)
+				}
+			}
+			t.Interactions = append(t.Interactions, interaction)
+		}
+		return &t, nil
+	}
+
+	if err := json.NewDecoder(f).Decode(&t); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать таблицу взаимодействий: %w", err)
+	}
+	return &t, nil
+}
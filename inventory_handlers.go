@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"take-a-pill/models"
+)
+
+// Обработчик для получения прогноза окончания запаса таблеток
+func (s *Server) getInventory(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	scheduleID := r.URL.Query().Get("schedule_id")
+
+	if _, ok := s.userSchedule(w, r, userID, scheduleID); !ok {
+		return
+	}
+
+	forecast, err := s.db.ForecastInventory(r.Context(), scheduleID)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeForecast(w, forecast)
+}
+
+// Обработчик для задания запаса таблеток
+func (s *Server) setInventory(w http.ResponseWriter, r *http.Request) {
+	var request models.InventoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		s.writeInvalidJSON(w, r, err)
+		return
+	}
+
+	if _, ok := s.userSchedule(w, r, request.UserID, request.ScheduleID); !ok {
+		return
+	}
+
+	forecast, err := s.db.SetInventory(r.Context(), request.ScheduleID, ifMatchVersion(r), request.Inventory)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeForecast(w, forecast)
+}
+
+// Обработчик для пополнения запаса таблеток
+func (s *Server) refillInventory(w http.ResponseWriter, r *http.Request) {
+	var request models.RefillRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		s.writeInvalidJSON(w, r, err)
+		return
+	}
+
+	if _, ok := s.userSchedule(w, r, request.UserID, request.ScheduleID); !ok {
+		return
+	}
+
+	forecast, err := s.db.RefillInventory(r.Context(), request.ScheduleID, ifMatchVersion(r), request.Packs)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeForecast(w, forecast)
+}
+
+// Обработчик для отметки о приеме лекарства
+func (s *Server) logDose(w http.ResponseWriter, r *http.Request) {
+	var request models.DoseRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		s.writeInvalidJSON(w, r, err)
+		return
+	}
+
+	if _, ok := s.userSchedule(w, r, request.UserID, request.ScheduleID); !ok {
+		return
+	}
+
+	forecast, err := s.db.LogDose(r.Context(), request.ScheduleID, ifMatchVersion(r))
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	// Если запас не ведется, просто подтверждаем прием
+	if forecast == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	s.writeForecast(w, forecast)
+}
+
+// writeForecast отправляет прогноз запаса таблеток в ответе
+func (s *Server) writeForecast(w http.ResponseWriter, forecast *models.InventoryForecast) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(forecast.ScheduleVersion))
+	if err := json.NewEncoder(w).Encode(forecast); err != nil {
+		s.logger.Warn("ошибка при отправке ответа", "error", err)
+	}
+}
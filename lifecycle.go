@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"take-a-pill/grpcapi"
+
+	"google.golang.org/grpc"
+)
+
+// Run запускает REST и gRPC API и фоновые задачи. При отмене контекста
+// сервис сначала в течение ShutdownDelay отвечает 503 на /readyz,
+// затем перестает принимать запросы, дожидается завершения начатых
+// и останавливает фоновые задачи.
+func (s *Server) Run(ctx context.Context) error {
+	httpListener, err := net.Listen("tcp", s.cfg.ServerPort)
+	if err != nil {
+		return err
+	}
+	grpcListener, err := net.Listen("tcp", s.cfg.GRPCPort)
+	if err != nil {
+		httpListener.Close()
+		return err
+	}
+	return s.serve(ctx, httpListener, grpcListener)
+}
+
+// serve обслуживает запросы на заданных адресах до отмены контекста
+// или ошибки одного из серверов
+func (s *Server) serve(ctx context.Context, httpListener, grpcListener net.Listener) error {
+	httpServer := &http.Server{
+		Handler:           s.router,
+		ReadHeaderTimeout: s.cfg.ReadHeaderTimeout,
+		ReadTimeout:       s.cfg.ReadTimeout,
+		WriteTimeout:      s.cfg.WriteTimeout,
+		IdleTimeout:       s.cfg.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(s.logger.Handler(), slog.LevelWarn),
+	}
+	grpcServer := grpc.NewServer()
+	grpcAPI := grpcapi.NewServer(s.db, s.logger)
+	grpcAPI.Register(grpcServer)
+
+	// Фоновые задачи останавливаются после того, как завершены все запросы
+	workers, stopWorkers := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.publishDueDoses(workers)
+	}()
+
+	errs := make(chan error, 2)
+	tls := s.cfg.TLSCertFile != "" || s.cfg.TLSKeyFile != ""
+	go func() {
+		var err error
+		if tls {
+			err = httpServer.ServeTLS(httpListener, s.cfg.TLSCertFile, s.cfg.TLSKeyFile)
+		} else {
+			err = httpServer.Serve(httpListener)
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			errs <- err
+		}
+	}()
+	go func() {
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			errs <- err
+		}
+	}()
+	s.logger.Info("сервер запущен", "addr", httpListener.Addr().String(), "tls", tls, "grpc_addr", grpcListener.Addr().String())
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+	case serveErr = <-errs:
+		s.logger.Error("ошибка сервера", "error", serveErr)
+	}
+
+	s.logger.Info("остановка сервиса")
+	s.draining.Store(true)
+	// Пока балансировщик не увидел 503 на /readyz, он еще присылает
+	// запросы, поэтому соединения принимаем до конца задержки
+	if serveErr == nil && s.cfg.ShutdownDelay > 0 {
+		time.Sleep(s.cfg.ShutdownDelay)
+	}
+	// Потоки событий не заканчиваются сами, поэтому закрываем их сразу.
+	// Клиенты переподключатся к другому экземпляру с Last-Event-ID.
+	close(s.closing)
+	grpcAPI.Close()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		s.logger.Warn("не все запросы завершились до остановки", "error", err)
+		httpServer.Close()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		grpcServer.Stop()
+	}
+
+	stopWorkers()
+	wg.Wait()
+	s.logger.Info("сервис остановлен")
+	return serveErr
+}
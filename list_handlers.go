@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"take-a-pill/models"
+	"take-a-pill/problem"
+	"take-a-pill/storage"
+)
+
+// Обработчик для получения страницы расписаний пользователя с фильтрами
+func (s *Server) listSchedules(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	userID := query.Get("user_id")
+	if userID == "" {
+		s.writeMissingParameter(w, r, "user_id")
+		return
+	}
+
+	opts := storage.ListOptions{
+		Status:   query.Get("status"),
+		Medicine: query.Get("medicine"),
+		Sort:     query.Get("sort"),
+		Cursor:   query.Get("cursor"),
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			s.writeProblem(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "limit")
+			return
+		}
+		opts.Limit = limit
+	}
+
+	schedules, next, err := s.db.ListSchedules(r.Context(), userID, opts)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.ScheduleListResponse{
+		Schedules:  schedules,
+		NextCursor: next,
+	})
+}
+
+// Обработчик для приостановки курса
+func (s *Server) pauseSchedule(w http.ResponseWriter, r *http.Request) {
+	s.setPaused(w, r, true)
+}
+
+// Обработчик для возобновления курса
+func (s *Server) resumeSchedule(w http.ResponseWriter, r *http.Request) {
+	s.setPaused(w, r, false)
+}
+
+// setPaused меняет состояние приостановки курса и возвращает расписание
+func (s *Server) setPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	var request models.PauseRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		s.writeInvalidJSON(w, r, err)
+		return
+	}
+
+	if _, ok := s.userSchedule(w, r, request.UserID, request.ScheduleID); !ok {
+		return
+	}
+
+	schedule, err := s.db.SetPaused(r.Context(), request.ScheduleID, ifMatchVersion(r), paused)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(schedule.Version))
+	json.NewEncoder(w).Encode(schedule)
+}
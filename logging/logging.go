@@ -0,0 +1,176 @@
+// Package logging настраивает структурированные логи сервиса и не дает
+// медицинским данным пользователей попасть в них
+package logging
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Форматы логов
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Значение, которым заменяются скрытые данные
+const redacted = "[скрыто]"
+
+// Ключи атрибутов с ID пользователя. В логах уровня info и выше
+// вместо ID пишется его хеш с ключом user_hash.
+var userKeys = map[string]bool{
+	"user_id": true,
+}
+
+// Ключи атрибутов с медицинскими данными. В логах уровня info и выше
+// их значения скрываются.
+var sensitiveKeys = map[string]bool{
+	"medicine_name": true,
+	"medicine":      true,
+	"medicine_id":   true,
+	"notification":  true,
+}
+
+// Options - настройки логов
+type Options struct {
+	// Минимальный уровень: debug, info, warn или error
+	Level string
+	// Формат: text или json
+	Format string
+	// Ключ для хеширования ID пользователей. Если не задан, выбирается
+	// случайно, и хеши одного пользователя совпадают только до перезапуска.
+	HashKey string
+}
+
+// New создает логгер с заданными настройками. Атрибуты с ID пользователей
+// и названиями лекарств скрываются во всех записях, кроме уровня debug.
+func New(w io.Writer, opts Options) (*slog.Logger, error) {
+	var level slog.Level
+	if opts.Level != "" {
+		if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+			return nil, fmt.Errorf("неизвестный уровень логов %q", opts.Level)
+		}
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", FormatText:
+		handler = slog.NewTextHandler(w, handlerOpts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		return nil, fmt.Errorf("неизвестный формат логов %q", opts.Format)
+	}
+
+	key := []byte(opts.HashKey)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return slog.New(&redactingHandler{next: handler, hashKey: key}), nil
+}
+
+// HashUserID возвращает хеш ID пользователя, по которому можно связать записи
+// одного пользователя, не раскрывая сам ID
+func HashUserID(key []byte, userID string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(userID))
+	return hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+// redactingHandler скрывает ID пользователей и медицинские данные
+type redactingHandler struct {
+	next    slog.Handler
+	hashKey []byte
+}
+
+func (h *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	// В отладочных логах данные остаются как есть
+	if record.Level < slog.LevelInfo {
+		return h.next.Handle(ctx, record)
+	}
+
+	clean := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		clean.AddAttrs(h.redact(attr))
+		return true
+	})
+	return h.next.Handle(ctx, clean)
+}
+
+// WithAttrs скрывает данные в общих атрибутах сразу, так как они попадут
+// в записи любого уровня
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		clean = append(clean, h.redact(attr))
+	}
+	return &redactingHandler{next: h.next.WithAttrs(clean), hashKey: h.hashKey}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{next: h.next.WithGroup(name), hashKey: h.hashKey}
+}
+
+// redact заменяет значение атрибута, если оно содержит данные пользователя
+func (h *redactingHandler) redact(attr slog.Attr) slog.Attr {
+	attr.Value = attr.Value.Resolve()
+
+	switch {
+	case userKeys[attr.Key]:
+		return slog.String("user_hash", HashUserID(h.hashKey, attr.Value.String()))
+	case sensitiveKeys[attr.Key]:
+		return slog.String(attr.Key, redacted)
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		clean := make([]any, 0, len(group))
+		for _, a := range group {
+			clean = append(clean, h.redact(a))
+		}
+		return slog.Group(attr.Key, clean...)
+	case slog.KindAny:
+		// Данные событий хранятся в картах
+		if data, ok := attr.Value.Any().(map[string]any); ok {
+			clean := make([]any, 0, len(data))
+			for key, value := range data {
+				clean = append(clean, h.redact(slog.Any(key, value)))
+			}
+			return slog.Group(attr.Key, clean...)
+		}
+	}
+	return attr
+}
+
+// contextKey - ключ логгера в контексте запроса
+type contextKey struct{}
+
+// WithLogger возвращает контекст с логгером запроса
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext возвращает логгер запроса или логгер по умолчанию
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"take-a-pill/logging"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Заголовок с ID запроса для связи записей в логах клиента и сервиса
+const headerRequestID = "X-Request-ID"
+
+// Максимальная длина ID запроса, полученного от клиента
+const maxRequestIDLength = 128
+
+// Служебные маршруты, которые часто опрашиваются мониторингом.
+// Успешные запросы к ним пишутся только в отладочный лог.
+var quietPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// statusRecorder запоминает статус ответа для записи в лог
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(data []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.ResponseWriter.Write(data)
+}
+
+// Flush нужен потоку событий, который отправляет данные по мере появления
+func (rec *statusRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap позволяет http.ResponseController управлять исходным соединением
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// logRequests добавляет в контекст запроса логгер с ID запроса и пользователя
+// и записывает в лог результат обработки запроса
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(headerRequestID)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(headerRequestID, requestID)
+
+		attrs := []any{"request_id", requestID, "method", r.Method, "path", r.URL.Path}
+		if userID := r.URL.Query().Get("user_id"); userID != "" {
+			// Вместо ID в лог попадет его хеш
+			attrs = append(attrs, "user_id", userID)
+		}
+		if span := trace.SpanContextFromContext(r.Context()); span.IsValid() {
+			// По ID трассировки можно найти запрос в системе трассировки
+			attrs = append(attrs, "trace_id", span.TraceID().String())
+		}
+		logger := s.logger.With(attrs...)
+
+		rec := &statusRecorder{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(rec, r.WithContext(logging.WithLogger(r.Context(), logger)))
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		switch {
+		case rec.status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case quietPaths[r.URL.Path] && rec.status < http.StatusBadRequest:
+			level = slog.LevelDebug
+		}
+		logger.Log(r.Context(), level, "запрос обработан", "status", rec.status, "duration", time.Since(start))
+	})
+}
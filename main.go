@@ -1,32 +1,160 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"take-a-pill/catalog"
+	"take-a-pill/clock"
+	"take-a-pill/config"
+	"take-a-pill/events"
+	"take-a-pill/i18n"
+	"take-a-pill/idempotency"
+	"take-a-pill/interactions"
+	"take-a-pill/logging"
+	"take-a-pill/metrics"
 	"take-a-pill/models"
+	"take-a-pill/openapi"
+	"take-a-pill/problem"
+	"take-a-pill/ratelimit"
 	"take-a-pill/storage"
+	"take-a-pill/tracing"
 
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
 // Структура для хранения данных сервера
 type Server struct {
+	// Настройки сервиса
+	cfg *config.Config
+	// Часы, по которым считаются приемы
+	clock clock.Clock
 	// Хранилище для расписаний
 	db *storage.MemoryStorage
 	// Роутер
 	router *mux.Router
+	// Проверка взаимодействия лекарств
+	interactions *interactions.Checker
+	// Справочник лекарств
+	catalog *catalog.Catalog
+	// Ответы на запросы с ключом идемпотентности
+	idempotency *idempotency.Store
+	// Проверка запросов и ответов по спецификации API
+	spec *openapi.Validator
+	// Последние события для потока событий
+	eventHistory *events.History
+	// Структурированный лог, скрывающий данные пользователей
+	logger *slog.Logger
+	// Метрики сервиса для Prometheus
+	metrics *metrics.Metrics
+	// Ограничение частоты запросов клиентов
+	limiter *ratelimit.Limiter
+	// Сервис останавливается и не принимает новые запросы
+	draining atomic.Bool
+	// Закрывается при остановке сервиса, чтобы завершить потоки событий
+	closing chan struct{}
 }
 
 // Создаем новый сервер
 func NewServer() *Server {
+	return NewServerWithConfig(config.DefaultConfig())
+}
+
+// NewServerWithConfig создает сервер с заданными настройками
+func NewServerWithConfig(cfg *config.Config) *Server {
+	return NewServerWithClock(cfg, clock.System{})
+}
+
+// NewServerWithClock создает сервер, который берет текущее время из заданных часов
+func NewServerWithClock(cfg *config.Config, c clock.Clock) *Server {
 	s := &Server{
-		db:     storage.NewMemoryStorage(),
-		router: mux.NewRouter(),
+		cfg:         cfg,
+		clock:       c,
+		db:          storage.NewMemoryStorageWithClock(cfg, c),
+		router:      mux.NewRouter(),
+		idempotency: idempotency.NewStore(cfg.IdempotencyTTL, c),
+		closing:     make(chan struct{}),
+		limiter:     ratelimit.New(c),
+	}
+
+	// Настраиваем лог. При ошибке в настройках пишем с настройками по умолчанию.
+	logger, err := logging.New(os.Stderr, logging.Options{
+		Level:   cfg.LogLevel,
+		Format:  cfg.LogFormat,
+		HashKey: cfg.LogHashKey,
+	})
+	if err != nil {
+		logger, _ = logging.New(os.Stderr, logging.Options{HashKey: cfg.LogHashKey})
+		logger.Warn("некорректные настройки лога", "error", err)
+	}
+	s.logger = logger
+
+	// Загружаем таблицу взаимодействий лекарств
+	checker, err := interactions.Load(cfg.InteractionsFile)
+	if err != nil {
+		s.logger.Warn("проверка взаимодействия лекарств отключена", "error", err)
+	} else {
+		s.interactions = checker
+		s.db.SetInteractionChecker(checker)
+	}
+
+	// Загружаем справочник лекарств
+	medicines, err := catalog.Load(cfg.CatalogFile)
+	if err != nil {
+		s.logger.Warn("справочник лекарств недоступен", "error", err)
+	} else {
+		s.catalog = medicines
+		s.db.SetCatalog(medicines)
 	}
 
+	// Загружаем максимальные суточные дозы веществ
+	limits, err := catalog.LoadLimits(cfg.DoseLimitsFile)
+	if err != nil {
+		s.logger.Warn("проверка суточных доз отключена", "error", err)
+	} else {
+		s.db.SetDoseLimits(limits)
+	}
+
+	// Загружаем спецификацию API, если нужно проверять запросы или ответы
+	if cfg.ValidateRequests || cfg.ValidateResponses {
+		spec, err := openapi.Load(cfg.OpenAPIFile)
+		if err != nil {
+			s.logger.Warn("проверка по спецификации API отключена", "error", err)
+		} else {
+			s.spec = spec
+		}
+	}
+
+	// Собираем метрики API и хранилища
+	s.metrics = metrics.New(s.db)
+	s.db.SetNextTakingsObserver(s.metrics.ObserveNextTakings)
+
+	// Храним последние события для продолжения потока после переподключения
+	s.eventHistory = events.NewHistory(s.db.Events(), cfg.EventHistorySize)
+
+	// Записываем события хранилища и тексты уведомлений в лог. Данные
+	// событий и тексты уведомлений содержат медицинские данные, и скрытие
+	// по ключам не защитит от новых полей, поэтому они пишутся только
+	// в отладочный лог.
+	s.db.Events().Subscribe(func(e events.Event) {
+		s.logger.Info("событие", "type", e.Type, "user_id", e.UserID, "schedule_id", e.ScheduleID)
+		s.logger.Debug("данные события", "type", e.Type, "schedule_id", e.ScheduleID, "data", e.Data)
+		if text := s.notificationText(e); text != "" {
+			s.logger.Debug("уведомление", "user_id", e.UserID, "notification", text)
+		}
+	})
+
 	// Настраиваем маршруты
 	s.routes()
 
@@ -35,18 +163,120 @@ func NewServer() *Server {
 
 // Настройка маршрутов
 func (s *Server) routes() {
+	// Начинаем трассировку запроса, продолжая трассировку клиента из заголовка traceparent
+	s.router.Use(otelmux.Middleware(serviceName), annotateSpan)
+
 	// Добавляем логирование для всех запросов
-	s.router.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			log.Printf("Получен запрос: %s %s", r.Method, r.URL.Path)
-			next.ServeHTTP(w, r)
-		})
+	s.router.Use(s.logRequests)
+	// Считаем запросы и время их обработки
+	s.router.Use(s.measureRequests)
+
+	// Ограничиваем частоту запросов и размер тела запроса
+	s.router.Use(s.rateLimit, s.limitBody)
+
+	// Проверяем запросы и ответы по спецификации API
+	if s.spec != nil {
+		s.router.Use(s.validateSpec)
+	}
+
+	// Ошибки маршрутизации тоже отдаем в формате problem+json
+	s.router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.writeProblem(w, r, http.StatusNotFound, problem.CodeNotFound)
+	})
+	s.router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed)
+	})
+
+	// Актуальная версия API. Маршруты регистрируются на корневом роутере,
+	// а не в подроутерах, чтобы для них корректно возвращался 405.
+	s.apiRoutes("/v1", false)
+	s.router.HandleFunc("/v1/schedules", s.listSchedules).Methods("GET")
+	s.router.HandleFunc("/v1/schedule/pause", s.requireIfMatch(s.pauseSchedule)).Methods("POST")
+	s.router.HandleFunc("/v1/schedule/resume", s.requireIfMatch(s.resumeSchedule)).Methods("POST")
+
+	// Маршруты без версии продолжают работать, но помечены устаревшими
+	s.apiRoutes("", true)
+	s.router.Handle("/schedules", deprecated(http.HandlerFunc(s.getSchedules))).Methods("GET")
+
+	// Служебные маршруты не относятся к API и не версионируются
+	s.router.Handle("/metrics", s.metrics.Handler()).Methods("GET")
+	s.router.HandleFunc("/healthz", s.healthz).Methods("GET")
+	s.router.HandleFunc("/readyz", s.readyz).Methods("GET")
+}
+
+// apiRoutes добавляет маршруты, общие для всех версий API, с заданным префиксом.
+// Устаревшие маршруты помечаются заголовками Deprecation.
+func (s *Server) apiRoutes(prefix string, legacy bool) {
+	handle := func(path, method string, handler http.HandlerFunc) {
+		var h http.Handler = handler
+		if legacy {
+			h = deprecated(h)
+		}
+		s.router.Handle(prefix+path, h).Methods(method)
+	}
+	// mutate добавляет маршрут, который изменяет расписание или настройки.
+	// Без If-Match изменения с двух устройств молча перезаписывали бы друг
+	// друга, поэтому заголовок обязателен и на устаревших маршрутах.
+	// Отметка о приеме и повторная выдача по рецепту только уменьшают
+	// счетчик под блокировкой и ничего не перезаписывают, поэтому If-Match
+	// для них необязателен: пациент отмечает прием сразу, без запроса ETag.
+	// От двойного учета при повторе их защищает Idempotency-Key.
+	mutate := func(path, method string, handler http.HandlerFunc) {
+		handle(path, method, s.requireIfMatch(handler))
+	}
+
+	handle("/schedule", "POST", s.idempotent(s.createSchedule))
+	handle("/schedule", "GET", s.getScheduleDetails)
+	handle("/schedule/preview", "POST", s.previewSchedule)
+	handle("/next_takings", "GET", s.getNextTakings)
+	handle("/schedule/inventory", "GET", s.getInventory)
+	mutate("/schedule/inventory", "PUT", s.setInventory)
+	mutate("/schedule/refill", "POST", s.refillInventory)
+	handle("/schedule/dose", "POST", s.idempotent(s.logDose))
+	handle("/prescription", "POST", s.createPrescription)
+	handle("/prescription", "GET", s.getPrescription)
+	handle("/prescriptions", "GET", s.getPrescriptions)
+	handle("/prescription/refill", "POST", s.idempotent(s.usePrescriptionRefill))
+	handle("/interactions/reload", "POST", s.reloadInteractions)
+	handle("/medicines/search", "GET", s.searchMedicines)
+	handle("/medicine", "GET", s.getMedicine)
+	handle("/profile", "GET", s.getProfile)
+	mutate("/profile", "PUT", s.setProfile)
+	handle("/events", "GET", s.streamEvents)
+	handle("/reminders/consolidate/preview", "POST", s.previewConsolidation)
+	mutate("/reminders/consolidate", "POST", s.idempotent(s.consolidateReminders))
+}
+
+// deprecated помечает ответы старых маршрутов без версии как устаревшие
+// и указывает на замену в /v1
+func deprecated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf(`</v1%s>; rel="successor-version"`, r.URL.Path))
+		next.ServeHTTP(w, r)
 	})
+}
+
+// userSchedule находит расписание и проверяет, что оно принадлежит пользователю.
+// При ошибке сам отправляет ответ клиенту и возвращает false.
+func (s *Server) userSchedule(w http.ResponseWriter, r *http.Request, userID, scheduleID string) (*models.Schedule, bool) {
+	if userID == "" {
+		s.writeMissingParameter(w, r, "user_id")
+		return nil, false
+	}
+
+	if scheduleID == "" {
+		s.writeMissingParameter(w, r, "schedule_id")
+		return nil, false
+	}
 
-	s.router.HandleFunc("/schedule", s.createSchedule).Methods("POST")
-	s.router.HandleFunc("/schedule", s.getScheduleDetails).Methods("GET")
-	s.router.HandleFunc("/schedules", s.getSchedules).Methods("GET")
-	s.router.HandleFunc("/next_takings", s.getNextTakings).Methods("GET")
+	schedule, err := s.db.GetScheduleByID(r.Context(), scheduleID)
+	if err != nil || schedule.UserID != userID {
+		s.writeProblem(w, r, http.StatusNotFound, storage.CodeScheduleNotFound)
+		return nil, false
+	}
+
+	return schedule, true
 }
 
 // Обработчик для создания расписания
@@ -55,21 +285,53 @@ func (s *Server) createSchedule(w http.ResponseWriter, r *http.Request) {
 	var request models.ScheduleRequest
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
-		http.Error(w, "Ошибка при чтении данных", http.StatusBadRequest)
+		s.writeInvalidJSON(w, r, err)
 		return
 	}
 
 	// Создаем расписание
-	schedule, err := s.db.CreateSchedule(&request)
+	schedule, warnings, err := s.db.CreateSchedule(r.Context(), &request)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.writeError(w, r, err)
 		return
 	}
 
+	// Переводим предупреждения на язык пользователя
+	lang := s.language(r, request.UserID)
+	for i := range warnings {
+		warnings[i].Message = i18n.T(lang, warnings[i].Code, warnings[i].Args...)
+	}
+
 	// Отправляем ответ
 	w.Header().Set("Content-Type", "application/json")
-	response := fmt.Sprintf(`{"schedule_id": "%s"}`, schedule.ID)
-	w.Write([]byte(response))
+	json.NewEncoder(w).Encode(models.CreateScheduleResponse{
+		ScheduleID: schedule.ID,
+		Warnings:   warnings,
+	})
+}
+
+// Обработчик для предварительного просмотра расписания без сохранения
+func (s *Server) previewSchedule(w http.ResponseWriter, r *http.Request) {
+	var request models.ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		s.writeInvalidJSON(w, r, err)
+		return
+	}
+
+	preview, err := s.db.PreviewSchedule(r.Context(), &request)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	// Переводим предупреждения на язык пользователя
+	lang := s.language(r, request.UserID)
+	for i := range preview.Warnings {
+		preview.Warnings[i].Message = i18n.T(lang, preview.Warnings[i].Code, preview.Warnings[i].Args...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
 }
 
 // Обработчик для получения списка расписаний пользователя
@@ -77,12 +339,12 @@ func (s *Server) getSchedules(w http.ResponseWriter, r *http.Request) {
 	// Получаем user_id из параметров запроса
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
-		http.Error(w, "не указан user_id", http.StatusBadRequest)
+		s.writeMissingParameter(w, r, "user_id")
 		return
 	}
 
 	// Получаем список расписаний
-	scheduleIDs := s.db.GetSchedulesByUserID(userID)
+	scheduleIDs := s.db.GetSchedulesByUserID(r.Context(), userID)
 
 	// Отправляем ответ
 	w.Header().Set("Content-Type", "application/json")
@@ -97,43 +359,48 @@ func (s *Server) getScheduleDetails(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
 	scheduleID := r.URL.Query().Get("schedule_id")
 
-	log.Printf("Получен запрос на детали расписания: user_id=%s, schedule_id=%s", userID, scheduleID)
+	logger := logging.FromContext(r.Context())
 
 	if userID == "" {
-		log.Println("Не указан user_id")
-		http.Error(w, "не указан user_id", http.StatusBadRequest)
+		s.writeMissingParameter(w, r, "user_id")
 		return
 	}
 
 	if scheduleID == "" {
-		log.Println("Не указан schedule_id")
-		http.Error(w, "не указан schedule_id", http.StatusBadRequest)
+		s.writeMissingParameter(w, r, "schedule_id")
 		return
 	}
 
 	// Получаем расписание из хранилища
-	schedule, err := s.db.GetScheduleByID(scheduleID)
+	schedule, err := s.db.GetScheduleByID(r.Context(), scheduleID)
 	if err != nil {
-		log.Printf("Ошибка при получении расписания: %v", err)
-		http.Error(w, err.Error(), http.StatusNotFound)
+		logger.Debug("расписание не найдено", "schedule_id", scheduleID, "error", err)
+		s.writeError(w, r, err)
 		return
 	}
 
 	// Проверяем, что расписание принадлежит пользователю
 	if schedule.UserID != userID {
-		log.Printf("Расписание %s не принадлежит пользователю %s", scheduleID, userID)
-		http.Error(w, "расписание не найдено", http.StatusNotFound)
+		logger.Warn("запрос расписания другого пользователя", "schedule_id", scheduleID)
+		s.writeProblem(w, r, http.StatusNotFound, storage.CodeScheduleNotFound)
+		return
+	}
+
+	// Клиент уже знает эту версию расписания
+	etag := versionETag(schedule.Version)
+	w.Header().Set("ETag", etag)
+	if noneMatch(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
 	// Отправляем ответ
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(schedule); err != nil {
-		log.Printf("Ошибка при отправке ответа: %v", err)
-		http.Error(w, "внутренняя ошибка сервера", http.StatusInternalServerError)
+		logger.Warn("ошибка при отправке ответа", "error", err)
 		return
 	}
-	log.Printf("Успешно отправлены детали расписания %s", scheduleID)
+	logger.Debug("отправлены детали расписания", "schedule_id", scheduleID, "medicine_name", schedule.MedicineName)
 }
 
 // Обработчик для получения следующих приемов
@@ -141,35 +408,145 @@ func (s *Server) getNextTakings(w http.ResponseWriter, r *http.Request) {
 	// Получаем user_id из параметров запроса
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
-		log.Println("Не указан user_id")
-		http.Error(w, "не указан user_id", http.StatusBadRequest)
+		s.writeMissingParameter(w, r, "user_id")
 		return
 	}
 
+	// Для отладки можно узнать приемы на заданный момент
+	now := s.clock.Now()
+	if value := r.URL.Query().Get("at"); value != "" {
+		if !s.isAdmin(r) {
+			s.writeProblem(w, r, http.StatusForbidden, problem.CodeAdminOnly, "at")
+			return
+		}
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			s.writeProblem(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "at")
+			return
+		}
+		// Приемы считаются по местному времени сервиса
+		now = at.In(now.Location())
+	}
+
 	// Получаем следующие приемы
-	nextTakings := s.db.GetNextTakings(userID)
+	nextTakings := s.db.GetNextTakingsAt(r.Context(), userID, now)
+
+	_, span := tracer.Start(r.Context(), "next_takings.encode")
+	body, err := json.Marshal(map[string][]models.NextTaking{
+		"takings": nextTakings,
+	})
+	span.End()
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	// Если список не изменился, клиенту, опрашивающему сервер, не нужно его заново скачивать
+	etag := contentETag(body)
+	w.Header().Set("ETag", etag)
+	if noneMatch(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 
 	// Отправляем ответ
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(map[string][]models.NextTaking{
-		"takings": nextTakings,
-	}); err != nil {
-		log.Printf("Ошибка при отправке ответа: %v", err)
-		http.Error(w, "внутренняя ошибка сервера", http.StatusInternalServerError)
+	logger := logging.FromContext(r.Context())
+	if _, err := w.Write(append(body, '\n')); err != nil {
+		logger.Warn("ошибка при отправке ответа", "error", err)
 		return
 	}
-	log.Printf("Успешно отправлены следующие приемы для пользователя %s", userID)
+	logger.Debug("отправлены ближайшие приемы", "takings", len(nextTakings))
+}
+
+// Код ошибки, когда таблица взаимодействий не загружена
+const codeInteractionsUnavailable = "interactions_unavailable"
+
+// Обработчик для перезагрузки таблицы взаимодействий лекарств.
+// Доступен только с ключом администратора.
+func (s *Server) reloadInteractions(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdmin(r) {
+		s.writeProblem(w, r, http.StatusForbidden, problem.CodeAdminRequired)
+		return
+	}
+	if s.interactions == nil {
+		s.writeProblem(w, r, http.StatusServiceUnavailable, codeInteractionsUnavailable)
+		return
+	}
+
+	if err := s.interactions.Reload(); err != nil {
+		logging.FromContext(r.Context()).Error("ошибка при перезагрузке таблицы взаимодействий", "error", err)
+		s.writeError(w, r, err)
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("таблица взаимодействий лекарств перезагружена")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// publishDueDoses периодически публикует события о наступившем времени приема
+// и о заканчивающемся запасе таблеток
+func (s *Server) publishDueDoses(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.DueDosesInterval)
+	defer ticker.Stop()
+
+	last := s.clock.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := s.clock.Now()
+			s.db.PublishDueDoses(ctx, last, now)
+			s.db.CheckLowStock(ctx)
+			last = now
+		}
+	}
 }
 
 func main() {
+	// Читаем настройки из переменных окружения и флагов
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "некорректные настройки:", err)
+		os.Exit(2)
+	}
+
 	// Создаем сервер
-	server := NewServer()
+	server := NewServerWithConfig(cfg)
+	slog.SetDefault(server.logger)
+	if cfg.LogHashKey == "" {
+		server.logger.Warn("не задан ключ LogHashKey, хеши пользователей в логах изменятся после перезапуска")
+	}
 
-	// Запускаем сервер
-	port := ":8081"
-	fmt.Printf("Сервер запущен на http://localhost%s\n", port)
-	err := http.ListenAndServe(port, server.router)
+	// Настраиваем отправку трассировок
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    server.cfg.TraceExporter,
+		Endpoint:    server.cfg.TraceEndpoint,
+		ServiceName: serviceName,
+		HashKey:     server.cfg.LogHashKey,
+	})
 	if err != nil {
-		log.Fatalf("Ошибка при запуске сервера: %v\n", err)
+		server.logger.Error("ошибка при настройке трассировки", "error", err)
+		os.Exit(1)
+	}
+
+	// Работаем до SIGINT или SIGTERM, затем плавно останавливаемся
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	exitCode := 0
+	if err := server.Run(ctx); err != nil {
+		server.logger.Error("ошибка при запуске сервера", "error", err)
+		exitCode = 1
+	}
+
+	// Отправляем оставшиеся трассировки
+	if err := shutdownTracing(context.Background()); err != nil {
+		server.logger.Warn("ошибка при отправке трассировок", "error", err)
 	}
+	os.Exit(exitCode)
 }
@@ -1,13 +1,49 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"take-a-pill/catalog"
+	"take-a-pill/clock"
+	"take-a-pill/config"
+	"take-a-pill/events"
+	"take-a-pill/grpcapi"
+	"take-a-pill/logging"
 	"take-a-pill/models"
+	"take-a-pill/pillpb"
+	"take-a-pill/problem"
+	"take-a-pill/storage"
+	"take-a-pill/timing"
+	"take-a-pill/tracing"
+	"take-a-pill/validation"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
 )
 
 // Тест на создание расписания
@@ -66,8 +102,56 @@ func TestCreateScheduleWithEmptyUserID(t *testing.T) {
 	w := httptest.NewRecorder()
 	server.router.ServeHTTP(w, req)
 
-	if w.Code != http.StatusInternalServerError {
-		t.Errorf("Ожидался статус 500, получен %d", w.Code)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400, получен %d", w.Code)
+	}
+
+	// Проверяем, что ошибка описана в формате problem+json с указанием поля
+	if w.Header().Get("Content-Type") != problem.ContentType {
+		t.Errorf("Неверный Content-Type: %s", w.Header().Get("Content-Type"))
+	}
+	var p problem.Problem
+	json.NewDecoder(w.Body).Decode(&p)
+	if p.Code != problem.CodeValidationFailed || len(p.Errors) != 1 || p.Errors[0].Field != "user_id" {
+		t.Errorf("Неверное описание ошибки: %+v", p)
+	}
+}
+
+// Тест на то, что возвращаются все ошибки валидации сразу
+func TestCreateScheduleReturnsAllFieldErrors(t *testing.T) {
+	server := NewServer()
+
+	data := models.ScheduleRequest{
+		UserID:    "",
+		Frequency: 30,
+		Duration:  -1,
+	}
+
+	jsonData, _ := json.Marshal(data)
+	req := httptest.NewRequest("POST", "/schedule", bytes.NewBuffer(jsonData))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400, получен %d", w.Code)
+	}
+
+	var p problem.Problem
+	json.NewDecoder(w.Body).Decode(&p)
+	fields := map[string]string{}
+	for _, fieldErr := range p.Errors {
+		fields[fieldErr.Field] = fieldErr.Code
+	}
+	expected := map[string]string{
+		"user_id":       validation.CodeRequired,
+		"medicine_name": validation.CodeRequired,
+		"frequency":     validation.CodeOutOfRange,
+		"duration":      validation.CodeNegative,
+	}
+	for field, code := range expected {
+		if fields[field] != code {
+			t.Errorf("Для поля %s ожидался код %s, получен %q", field, code, fields[field])
+		}
 	}
 }
 
@@ -103,7 +187,9 @@ func TestBadJSON(t *testing.T) {
 	if w.Code != http.StatusBadRequest {
 		t.Error("Должен быть код 400")
 	}
-	if w.Body.String() != "Ошибка при чтении данных\n" {
+	var p problem.Problem
+	json.NewDecoder(w.Body).Decode(&p)
+	if p.Code != problem.CodeInvalidJSON || p.Detail != "Ошибка при чтении данных" {
 		t.Error("Неправильная ошибка")
 	}
 }
@@ -188,60 +274,2514 @@ func TestGetScheduleDetails(t *testing.T) {
 }
 
 func TestGetNextTakings(t *testing.T) {
-	server := NewServer()
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skipf("нет данных о часовых поясах: %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("нет данных о часовых поясах: %v", err)
+	}
 
-	// Создаем несколько расписаний с разными временами приема
-	schedules := []models.ScheduleRequest{
+	// Аспирин принимается в 11:30, 15:00 и 18:30, витамин С - в 12:45 и 17:15
+	tests := []struct {
+		name    string
+		created time.Time
+		now     time.Time
+		want    []string
+	}{
 		{
-			UserID:       "test123",
-			MedicineName: "Аспирин",
-			Frequency:    3,
-			Duration:     7,
+			name:    "утро",
+			created: time.Date(2024, 5, 1, 7, 0, 0, 0, moscow),
+			now:     time.Date(2024, 5, 1, 7, 30, 0, 0, moscow),
+			want:    []string{"Аспирин 11:30", "Витамин С 12:45", "Аспирин 15:00", "Витамин С 17:15", "Аспирин 18:30"},
 		},
 		{
-			UserID:       "test123",
-			MedicineName: "Витамин С",
-			Frequency:    2,
-			Duration:     14,
+			name:    "время приема еще не прошло",
+			created: time.Date(2024, 5, 1, 7, 0, 0, 0, moscow),
+			now:     time.Date(2024, 5, 1, 15, 0, 0, 0, moscow),
+			want:    []string{"Аспирин 15:00", "Витамин С 17:15", "Аспирин 18:30"},
+		},
+		{
+			name:    "вечер после последнего приема",
+			created: time.Date(2024, 5, 1, 7, 0, 0, 0, moscow),
+			now:     time.Date(2024, 5, 1, 21, 0, 0, 0, moscow),
+		},
+		{
+			name:    "полночь",
+			created: time.Date(2024, 5, 1, 7, 0, 0, 0, moscow),
+			now:     time.Date(2024, 5, 2, 0, 0, 0, 0, moscow),
+			want:    []string{"Аспирин 11:30", "Витамин С 12:45", "Аспирин 15:00", "Витамин С 17:15", "Аспирин 18:30"},
+		},
+		{
+			name:    "курс аспирина закончился",
+			created: time.Date(2024, 5, 1, 7, 0, 0, 0, moscow),
+			now:     time.Date(2024, 5, 8, 9, 0, 0, 0, moscow),
+			want:    []string{"Витамин С 12:45", "Витамин С 17:15"},
+		},
+		{
+			name:    "переход на летнее время",
+			created: time.Date(2024, 3, 9, 20, 0, 0, 0, newYork),
+			now:     time.Date(2024, 3, 10, 13, 0, 0, 0, newYork),
+			want:    []string{"Аспирин 15:00", "Витамин С 17:15", "Аспирин 18:30"},
+		},
+		{
+			name:    "переход на зимнее время",
+			created: time.Date(2024, 11, 2, 20, 0, 0, 0, newYork),
+			now:     time.Date(2024, 11, 3, 1, 30, 0, 0, newYork).Add(time.Hour),
+			want:    []string{"Аспирин 11:30", "Витамин С 12:45", "Аспирин 15:00", "Витамин С 17:15", "Аспирин 18:30"},
 		},
 	}
 
-	// Создаем расписания
-	for _, schedule := range schedules {
-		jsonData, _ := json.Marshal(schedule)
-		req := httptest.NewRequest("POST", "/schedule", bytes.NewBuffer(jsonData))
-		req.Header.Set("Content-Type", "application/json")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(tt.created)
+			server := NewServerWithClock(config.DefaultConfig(), clk)
+
+			for _, schedule := range []models.ScheduleRequest{
+				{UserID: "test123", MedicineName: "Аспирин", Frequency: 3, Duration: 7},
+				{UserID: "test123", MedicineName: "Витамин С", Frequency: 2, Duration: 14},
+			} {
+				jsonData, _ := json.Marshal(schedule)
+				req := httptest.NewRequest("POST", "/schedule", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				server.router.ServeHTTP(w, req)
+				if w.Code != http.StatusOK {
+					t.Fatalf("Ожидался статус 200 при создании расписания, получен %d: %s", w.Code, w.Body)
+				}
+			}
+
+			clk.Set(tt.now)
+			req := httptest.NewRequest("GET", "/next_takings?user_id=test123", nil)
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+			}
+
+			var response map[string][]models.NextTaking
+			json.NewDecoder(w.Body).Decode(&response)
+			var got []string
+			for _, taking := range response["takings"] {
+				if taking.ScheduleID == "" {
+					t.Error("Пустой ID расписания")
+				}
+				got = append(got, fmt.Sprintf("%s %02d:%02d", taking.MedicineName, taking.NextTakingTime.Hour, taking.NextTakingTime.Minute))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Ожидались приемы %v, получены %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGetNextTakingsAt(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.AdminAPIKey = "admin-secret"
+	clk := clock.NewFake(time.Date(2024, 5, 1, 7, 0, 0, 0, time.UTC))
+	server := NewServerWithClock(cfg, clk)
+
+	jsonData, _ := json.Marshal(models.ScheduleRequest{UserID: "test123", MedicineName: "Аспирин", Frequency: 3, Duration: 7})
+	req := httptest.NewRequest("POST", "/schedule", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	server.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	nextTakings := func(at, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/v1/next_takings?user_id=test123&at="+url.QueryEscape(at), nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
 		w := httptest.NewRecorder()
 		server.router.ServeHTTP(w, req)
+		return w
 	}
 
-	// Получаем следующие приемы
-	req := httptest.NewRequest("GET", "/next_takings?user_id=test123", nil)
-	w := httptest.NewRecorder()
-	server.router.ServeHTTP(w, req)
+	// Без ключа администратора параметр недоступен
+	if w := nextTakings("2024-05-01T13:00:00Z", ""); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидался статус 403 без ключа, получен %d", w.Code)
+	}
+	if w := nextTakings("2024-05-01T13:00:00Z", "wrong"); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидался статус 403 с неверным ключом, получен %d", w.Code)
+	}
+	if w := nextTakings("завтра", "admin-secret"); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для неверного времени, получен %d", w.Code)
+	}
 
-	// Проверяем статус
+	// Момент с другим смещением переводится во время сервиса
+	w := nextTakings("2024-05-01T18:00:00+02:00", "admin-secret")
 	if w.Code != http.StatusOK {
-		t.Errorf("Ожидался статус 200, получен %d", w.Code)
+		t.Fatalf("Ожидался статус 200, получен %d: %s", w.Code, w.Body)
 	}
-
-	// Проверяем данные
 	var response map[string][]models.NextTaking
 	json.NewDecoder(w.Body).Decode(&response)
-	if len(response["takings"]) == 0 {
-		t.Error("Список следующих приемов пуст")
+	if len(response["takings"]) != 1 || response["takings"][0].NextTakingTime.Hour != 18 {
+		t.Errorf("Ожидался один прием в 18:30, получено %+v", response["takings"])
+	}
+
+	// Часы сервиса при этом не меняются
+	if !clk.Now().Equal(time.Date(2024, 5, 1, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("Часы сервиса изменились: %v", clk.Now())
+	}
+}
+
+func TestSchedulePreview(t *testing.T) {
+	server := NewServerWithClock(config.DefaultConfig(), clock.NewFake(time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)))
+
+	preview := func(request models.ScheduleRequest) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(request)
+		req := httptest.NewRequest("POST", "/v1/schedule/preview", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 5 раз в день на 10 дней
+	w := preview(models.ScheduleRequest{UserID: "test123", MedicineName: "Аспирин", Frequency: 5, Duration: 10})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d: %s", w.Code, w.Body)
+	}
+	var response models.SchedulePreview
+	json.NewDecoder(w.Body).Decode(&response)
+
+	wantTimes := []models.TakingTime{{Hour: 10, Minute: 15}, {Hour: 12, Minute: 45}, {Hour: 15}, {Hour: 17, Minute: 15}, {Hour: 19, Minute: 45}}
+	if !reflect.DeepEqual(response.TakingTimes, wantTimes) {
+		t.Errorf("Ожидались времена приема %v, получены %v", wantTimes, response.TakingTimes)
+	}
+	if want := time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC); !response.FirstDose.Equal(want) {
+		t.Errorf("Ожидался первый прием %v, получен %v", want, response.FirstDose)
+	}
+	if want := time.Date(2024, 5, 11, 12, 45, 0, 0, time.UTC); response.LastDose == nil || !response.LastDose.Equal(want) {
+		t.Errorf("Ожидался последний прием %v, получен %v", want, response.LastDose)
+	}
+	if response.TotalDoses != 50 {
+		t.Errorf("Ожидалось 50 приемов, получено %d", response.TotalDoses)
+	}
+
+	// Для постоянного приема последнего приема нет
+	w = preview(models.ScheduleRequest{UserID: "test123", MedicineName: "Витамин С", Frequency: 1})
+	response = models.SchedulePreview{}
+	json.NewDecoder(w.Body).Decode(&response)
+	if w.Code != http.StatusOK || response.LastDose != nil || response.TotalDoses != 0 {
+		t.Errorf("Неверный просмотр постоянного приема: %d %+v", w.Code, response)
+	}
+	if want := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC); !response.FirstDose.Equal(want) {
+		t.Errorf("Ожидался первый прием %v, получен %v", want, response.FirstDose)
+	}
+
+	// Запрос проверяется так же, как при создании
+	if w := preview(models.ScheduleRequest{UserID: "test123", MedicineName: "Аспирин", Frequency: 30}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для неверной частоты, получен %d", w.Code)
+	}
+
+	// Просмотр ничего не сохраняет
+	if ids := server.db.GetSchedulesByUserID(context.Background(), "test123"); len(ids) != 0 {
+		t.Errorf("Просмотр сохранил расписания: %v", ids)
+	}
+}
+
+func TestTakingTimeDistribution(t *testing.T) {
+	opts := timing.Options{
+		DayStart:    8 * time.Hour,
+		DayEnd:      22 * time.Hour,
+		Granularity: 15 * time.Minute,
+		Meals:       []time.Duration{8 * time.Hour, 13 * time.Hour, 19 * time.Hour},
+	}
+
+	// Любой способ дает ровно столько разных времен, сколько приемов в день
+	for _, strategy := range []string{timing.StrategyEven, timing.StrategyFrontLoaded, timing.StrategyMealAnchored} {
+		distributor, err := timing.New(strategy, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", strategy, err)
+		}
+		for frequency := 1; frequency <= 24; frequency++ {
+			times, err := distributor.Distribute(frequency)
+			if err != nil {
+				t.Errorf("%s, %d раз в день: %v", strategy, frequency, err)
+				continue
+			}
+			if len(times) != frequency {
+				t.Errorf("%s, %d раз в день: получено %d времен %v", strategy, frequency, len(times), times)
+			}
+			for i, tt := range times {
+				if tt.Hour < 8 || tt.Hour >= 22 || tt.Minute%15 != 0 {
+					t.Errorf("%s, %d раз в день: время %v вне сетки", strategy, frequency, tt)
+				}
+				if i > 0 && tt.Hour*60+tt.Minute <= times[i-1].Hour*60+times[i-1].Minute {
+					t.Errorf("%s, %d раз в день: времена не различаются или не отсортированы %v", strategy, frequency, times)
+				}
+			}
+		}
+	}
+
+	tests := []struct {
+		strategy  string
+		frequency int
+		want      []models.TakingTime
+	}{
+		{timing.StrategyEven, 1, []models.TakingTime{{Hour: 9}}},
+		{timing.StrategyEven, 3, []models.TakingTime{{Hour: 11, Minute: 30}, {Hour: 15}, {Hour: 18, Minute: 30}}},
+		{timing.StrategyFrontLoaded, 2, []models.TakingTime{{Hour: 8}, {Hour: 15}}},
+		{timing.StrategyMealAnchored, 1, []models.TakingTime{{Hour: 8}}},
+		{timing.StrategyMealAnchored, 2, []models.TakingTime{{Hour: 8}, {Hour: 19}}},
+		{timing.StrategyMealAnchored, 3, []models.TakingTime{{Hour: 8}, {Hour: 13}, {Hour: 19}}},
+		{timing.StrategyMealAnchored, 4, []models.TakingTime{{Hour: 8}, {Hour: 13}, {Hour: 16}, {Hour: 19}}},
+	}
+	for _, tt := range tests {
+		distributor, _ := timing.New(tt.strategy, opts)
+		if got, _ := distributor.Distribute(tt.frequency); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s, %d раз в день: ожидалось %v, получено %v", tt.strategy, tt.frequency, tt.want, got)
+		}
+	}
+
+	// С часовым шагом 24 приема в день не помещаются в окно 8:00-22:00
+	cfg := config.DefaultConfig()
+	cfg.TakingTimesGranularity = time.Hour
+	server := NewServerWithConfig(cfg)
+	create := func(request models.ScheduleRequest) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(request)
+		req := httptest.NewRequest("POST", "/v1/schedule", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	w := create(models.ScheduleRequest{UserID: "test123", MedicineName: "Аспирин", Frequency: 24})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), validation.CodeTooFrequent) {
+		t.Errorf("Ожидалась ошибка %s, получен статус %d: %s", validation.CodeTooFrequent, w.Code, w.Body)
+	}
+	if w := create(models.ScheduleRequest{UserID: "test123", MedicineName: "Аспирин", Frequency: 2, Distribution: "random"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для неизвестного способа, получен %d", w.Code)
+	}
+
+	// Способ распределения сохраняется в расписании
+	var created models.CreateScheduleResponse
+	json.NewDecoder(create(models.ScheduleRequest{UserID: "test123", MedicineName: "Аспирин", Frequency: 3, Distribution: timing.StrategyMealAnchored}).Body).Decode(&created)
+	schedule, err := server.db.GetScheduleByID(context.Background(), created.ScheduleID)
+	if err != nil {
+		t.Fatalf("Расписание не создано: %v", err)
+	}
+	if schedule.Distribution != timing.StrategyMealAnchored || !reflect.DeepEqual(schedule.TakingTimes, []models.TakingTime{{Hour: 8}, {Hour: 13}, {Hour: 19}}) {
+		t.Errorf("Неверное расписание: %s %v", schedule.Distribution, schedule.TakingTimes)
+	}
+}
+
+func TestMealRelativeDosing(t *testing.T) {
+	server := NewServerWithClock(config.DefaultConfig(), clock.NewFake(time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC)))
+
+	var updated []events.Event
+	server.db.Events().Subscribe(func(e events.Event) {
+		if e.Type == events.TypeScheduleUpdated {
+			updated = append(updated, e)
+		}
+	})
+
+	// Настройки сохраняются с ETag из предыдущего ответа, первый раз - с "*"
+	profileTag := "*"
+	send := func(method, path string, body any) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		if path == "/v1/profile" {
+			req.Header.Set("If-Match", profileTag)
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if path == "/v1/profile" && w.Code == http.StatusOK {
+			profileTag = w.Header().Get("ETag")
+		}
+		return w
+	}
+	create := func(request models.ScheduleRequest) *models.Schedule {
+		t.Helper()
+		w := send("POST", "/v1/schedule", request)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Ожидался статус 200, получен %d: %s", w.Code, w.Body)
+		}
+		var created models.CreateScheduleResponse
+		json.NewDecoder(w.Body).Decode(&created)
+		schedule, _ := server.db.GetScheduleByID(context.Background(), created.ScheduleID)
+		return schedule
+	}
+	// Хранилище отдает копии, поэтому изменения нужно перечитывать
+	reload := func(schedule *models.Schedule) *models.Schedule {
+		t.Helper()
+		reloaded, err := server.db.GetScheduleByID(context.Background(), schedule.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return reloaded
+	}
+
+	// Завтрак в 8:30, ужин в 20:00, обед по умолчанию в 13:00
+	w := send("PUT", "/v1/profile", models.UserProfile{UserID: "test123", Meals: map[string]models.TakingTime{
+		models.MealBreakfast: {Hour: 8, Minute: 30},
+		models.MealDinner:    {Hour: 20},
+	}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d: %s", w.Code, w.Body)
+	}
+
+	// За 30 минут до завтрака и во время ужина, частота не указана
+	meals := create(models.ScheduleRequest{UserID: "test123", MedicineName: "Левотироксин", Duration: 10, MealDoses: []models.MealDose{
+		{Meal: models.MealDinner, Relation: models.MealRelationWith},
+		{Meal: models.MealBreakfast, Relation: models.MealRelationBefore, OffsetMinutes: 30},
+	}})
+	if want := []models.TakingTime{{Hour: 8}, {Hour: 20}}; meals.Frequency != 2 || !reflect.DeepEqual(meals.TakingTimes, want) {
+		t.Errorf("Ожидались 2 приема %v, получено %d %v", want, meals.Frequency, meals.TakingTimes)
+	}
+	anchored := create(models.ScheduleRequest{UserID: "test123", MedicineName: "Ферменты", Frequency: 3, Distribution: timing.StrategyMealAnchored})
+	if want := []models.TakingTime{{Hour: 8, Minute: 30}, {Hour: 13}, {Hour: 20}}; !reflect.DeepEqual(anchored.TakingTimes, want) {
+		t.Errorf("Ожидались приемы во время еды %v, получено %v", want, anchored.TakingTimes)
+	}
+	plain := create(models.ScheduleRequest{UserID: "test123", MedicineName: "Аспирин", Frequency: 2})
+
+	// Ошибки в приемах относительно еды
+	invalid := []models.ScheduleRequest{
+		{UserID: "test123", MedicineName: "Аспирин", MealDoses: []models.MealDose{{Meal: models.MealLunch, Relation: "during"}}},
+		{UserID: "test123", MedicineName: "Аспирин", MealDoses: []models.MealDose{{Meal: "brunch", Relation: models.MealRelationWith}}},
+		{UserID: "test123", MedicineName: "Аспирин", Frequency: 3, MealDoses: []models.MealDose{{Meal: models.MealLunch, Relation: models.MealRelationWith}}},
+		{UserID: "test123", MedicineName: "Аспирин", MealDoses: []models.MealDose{
+			{Meal: models.MealLunch, Relation: models.MealRelationWith},
+			{Meal: models.MealLunch, Relation: models.MealRelationAfter},
+		}},
+	}
+	for _, request := range invalid {
+		if w := send("POST", "/v1/schedule", request); w.Code != http.StatusBadRequest {
+			t.Errorf("Ожидался статус 400 для %+v, получен %d", request.MealDoses, w.Code)
+		}
+	}
+
+	// Завтрак перенесли на 9:00 - курсы, привязанные к еде, пересчитываются
+	w = send("PUT", "/v1/profile", models.UserProfile{UserID: "test123", Meals: map[string]models.TakingTime{
+		models.MealBreakfast: {Hour: 9},
+		models.MealDinner:    {Hour: 20},
+	}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d: %s", w.Code, w.Body)
+	}
+	meals, anchored, plain = reload(meals), reload(anchored), reload(plain)
+	if want := []models.TakingTime{{Hour: 8, Minute: 30}, {Hour: 20}}; !reflect.DeepEqual(meals.TakingTimes, want) || meals.Version != 2 {
+		t.Errorf("Ожидались приемы %v в версии 2, получено %v в версии %d", want, meals.TakingTimes, meals.Version)
+	}
+	if want := []models.TakingTime{{Hour: 9}, {Hour: 13}, {Hour: 20}}; !reflect.DeepEqual(anchored.TakingTimes, want) {
+		t.Errorf("Ожидались приемы %v, получено %v", want, anchored.TakingTimes)
+	}
+	if plain.Version != 1 {
+		t.Error("Изменился курс, не привязанный к еде")
+	}
+	if len(updated) != 2 {
+		t.Errorf("Ожидалось 2 события об изменении курсов, получено %d", len(updated))
+	}
+
+	// Ближайшие приемы считаются по новому времени
+	takings := server.db.GetNextTakings(context.Background(), "test123")
+	if len(takings) == 0 || takings[0].ScheduleID != meals.ID || takings[0].NextTakingTime != (models.TakingTime{Hour: 8, Minute: 30}) {
+		t.Errorf("Первым ожидался прием в 8:30, получено %+v", takings)
+	}
+
+	// Если после изменения времени еды приемы совпадут, настройки не меняются
+	create(models.ScheduleRequest{UserID: "test123", MedicineName: "Кальций", MealDoses: []models.MealDose{
+		{Meal: models.MealBreakfast, Relation: models.MealRelationAfter, OffsetMinutes: 120},
+		{Meal: models.MealLunch, Relation: models.MealRelationBefore, OffsetMinutes: 60},
+	}})
+	w = send("PUT", "/v1/profile", models.UserProfile{UserID: "test123", Meals: map[string]models.TakingTime{
+		models.MealBreakfast: {Hour: 9},
+		models.MealLunch:     {Hour: 12},
+	}})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400, получен %d: %s", w.Code, w.Body)
+	}
+	profile, _ := server.db.GetProfile(context.Background(), "test123")
+	meals = reload(meals)
+	if profile.Meals[models.MealDinner] != (models.TakingTime{Hour: 20}) || meals.Version != 2 {
+		t.Errorf("Настройки или курсы изменились после ошибки: %+v, версия %d", profile.Meals, meals.Version)
+	}
+
+	// Без If-Match настройки не меняются, с устаревшим ETag - тоже
+	profileTag = ""
+	if w = send("PUT", "/v1/profile", models.UserProfile{UserID: "test123"}); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("Ожидался статус 428, получен %d", w.Code)
+	}
+	profileTag = `"1"`
+	w = send("PUT", "/v1/profile", models.UserProfile{UserID: "test123"})
+	if w.Code != http.StatusPreconditionFailed || !strings.Contains(w.Body.String(), storage.CodeProfileChanged) {
+		t.Errorf("Ожидалась ошибка 412 %s, получен %d: %s", storage.CodeProfileChanged, w.Code, w.Body)
+	}
+	if profile, _ := server.db.GetProfile(context.Background(), "test123"); profile.Version != 2 || len(profile.Meals) != 2 {
+		t.Errorf("Настройки изменились без актуального ETag: %+v", profile)
+	}
+}
+
+func TestDoseSeparation(t *testing.T) {
+	server := NewServerWithClock(config.DefaultConfig(), clock.NewFake(time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC)))
+	if server.interactions == nil {
+		t.Fatal("Таблица взаимодействий не загружена")
+	}
+
+	var updated []events.Event
+	server.db.Events().Subscribe(func(e events.Event) {
+		if e.Type == events.TypeScheduleUpdated {
+			updated = append(updated, e)
+		}
+	})
+
+	send := func(path string, request models.ScheduleRequest) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(request)
+		req := httptest.NewRequest("POST", path, bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	create := func(request models.ScheduleRequest) (*models.Schedule, []models.Warning) {
+		t.Helper()
+		w := send("/v1/schedule", request)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Ожидался статус 200, получен %d: %s", w.Code, w.Body)
+		}
+		var created models.CreateScheduleResponse
+		json.NewDecoder(w.Body).Decode(&created)
+		schedule, _ := server.db.GetScheduleByID(context.Background(), created.ScheduleID)
+		return schedule, created.Warnings
+	}
+	shifted := func(warnings []models.Warning) int {
+		count := 0
+		for _, w := range warnings {
+			if w.Code == storage.WarningDosesShifted {
+				count++
+			}
+		}
+		return count
+	}
+
+	// Левотироксин в 9:00, кальций должен быть не ближе 4 часов и сдвигается
+	// с 12:45 и 17:15 на 13:00 и 17:30
+	levothyroxine, _ := create(models.ScheduleRequest{UserID: "user1", MedicineName: "Эутирокс", Frequency: 1})
+	calcium, warnings := create(models.ScheduleRequest{UserID: "user1", MedicineName: "Кальций Д3 Никомед", Frequency: 2})
+	if want := []models.TakingTime{{Hour: 13}, {Hour: 17, Minute: 30}}; !reflect.DeepEqual(calcium.TakingTimes, want) {
+		t.Errorf("Ожидались приемы кальция %v, получены %v", want, calcium.TakingTimes)
+	}
+	if levothyroxine.Version != 1 || shifted(warnings) != 1 {
+		t.Errorf("Ожидалось, что сдвинется только новый курс: версия %d, предупреждения %+v", levothyroxine.Version, warnings)
+	}
+
+	// Левотироксин за 30 минут до завтрака нельзя сдвигать, поэтому сдвигается
+	// уже назначенный кальций с 9:00 на 11:30
+	calcium, _ = create(models.ScheduleRequest{UserID: "user2", MedicineName: "Кальций Д3 Никомед", Frequency: 1})
+	mealDoses := []models.MealDose{{Meal: models.MealBreakfast, Relation: models.MealRelationBefore, OffsetMinutes: 30}}
+	w := send("/v1/schedule/preview", models.ScheduleRequest{UserID: "user2", MedicineName: "Эутирокс", MealDoses: mealDoses})
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), storage.WarningDosesShifted) || calcium.Version != 1 {
+		t.Errorf("Просмотр должен предупредить о сдвиге, не меняя курсы: %d %s, версия %d", w.Code, w.Body, calcium.Version)
+	}
+	create(models.ScheduleRequest{UserID: "user2", MedicineName: "Эутирокс", MealDoses: mealDoses})
+	calcium, _ = server.db.GetScheduleByID(context.Background(), calcium.ID)
+	if want := []models.TakingTime{{Hour: 11, Minute: 30}}; !reflect.DeepEqual(calcium.TakingTimes, want) || calcium.Version != 2 {
+		t.Errorf("Ожидался прием кальция %v в версии 2, получен %v в версии %d", want, calcium.TakingTimes, calcium.Version)
+	}
+	if len(updated) != 1 || updated[0].ScheduleID != calcium.ID {
+		t.Errorf("Ожидалось событие об изменении курса кальция, получено %+v", updated)
+	}
+	takings := server.db.GetNextTakings(context.Background(), "user2")
+	if len(takings) != 2 || takings[1].NextTakingTime != (models.TakingTime{Hour: 11, Minute: 30}) {
+		t.Errorf("Ближайшие приемы не учитывают сдвиг: %+v", takings)
+	}
+
+	// Кальций во время каждой еды не оставляет места для левотироксина
+	create(models.ScheduleRequest{UserID: "user3", MedicineName: "Кальций Д3 Никомед", Distribution: timing.StrategyMealAnchored, Frequency: 3})
+	w = send("/v1/schedule", models.ScheduleRequest{UserID: "user3", MedicineName: "Эутирокс", Frequency: 1})
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), storage.CodeSeparationConflict) {
+		t.Errorf("Ожидалась ошибка %s, получен статус %d: %s", storage.CodeSeparationConflict, w.Code, w.Body)
+	}
+}
+
+func TestReminderConsolidation(t *testing.T) {
+	server := NewServerWithClock(config.DefaultConfig(), clock.NewFake(time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC)))
+	ctx := context.Background()
+
+	var schedules []*models.Schedule
+	for _, request := range []models.ScheduleRequest{
+		{UserID: "user1", MedicineName: "Эутирокс", Frequency: 1},
+		{UserID: "user1", MedicineName: "Кальций Д3 Никомед", Frequency: 2},
+		{UserID: "user1", MedicineName: "Аспирин", Frequency: 3},
+		{UserID: "user1", MedicineName: "Омега-3", Frequency: 2, Distribution: timing.StrategyMealAnchored},
+		{UserID: "user1", MedicineName: "Магний", Frequency: 1},
+	} {
+		schedule, _, err := server.db.CreateSchedule(ctx, &request)
+		if err != nil {
+			t.Fatalf("Не удалось создать расписание %s: %v", request.MedicineName, err)
+		}
+		schedules = append(schedules, schedule)
 	}
+	levothyroxine, calcium, aspirin, omega, magnesium := schedules[0], schedules[1], schedules[2], schedules[3], schedules[4]
+	if _, err := server.db.SetPaused(ctx, magnesium.ID, 0, true); err != nil {
+		t.Fatal(err)
+	}
+
+	var updated []events.Event
+	server.db.Events().Subscribe(func(e events.Event) {
+		if e.Type == events.TypeScheduleUpdated {
+			updated = append(updated, e)
+		}
+	})
 
-	// Проверяем структуру данных
-	for _, taking := range response["takings"] {
-		if taking.ScheduleID == "" {
-			t.Error("Пустой ID расписания")
+	send := func(path, ifMatch string, body any) (*httptest.ResponseRecorder, models.Consolidation) {
+		jsonData, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", path, bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
 		}
-		if taking.MedicineName == "" {
-			t.Error("Пустое название лекарства")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		var consolidation models.Consolidation
+		json.Unmarshal(w.Body.Bytes(), &consolidation)
+		return w, consolidation
+	}
+	moments := func(reminders []models.Reminder) []models.TakingTime {
+		var times []models.TakingTime
+		for _, r := range reminders {
+			times = append(times, r.Time)
+		}
+		return times
+	}
+
+	// Просмотр показывает 8 напоминаний до и 5 после, ничего не меняя
+	w, preview := send("/v1/reminders/consolidate/preview", "", models.ConsolidationRequest{UserID: "user1"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d: %s", w.Code, w.Body)
+	}
+	etag := w.Header().Get("ETag")
+	before := []models.TakingTime{{Hour: 8}, {Hour: 9}, {Hour: 11, Minute: 30}, {Hour: 13}, {Hour: 15}, {Hour: 17, Minute: 30}, {Hour: 18, Minute: 30}, {Hour: 19}}
+	after := []models.TakingTime{{Hour: 8}, {Hour: 12}, {Hour: 15, Minute: 30}, {Hour: 16, Minute: 30}, {Hour: 19}}
+	if !reflect.DeepEqual(moments(preview.Before), before) || !reflect.DeepEqual(moments(preview.After), after) {
+		t.Errorf("Ожидались напоминания %v -> %v, получены %v -> %v", before, after, moments(preview.Before), moments(preview.After))
+	}
+	if preview.Applied || len(preview.Changes) != 3 || levothyroxine.Version != 1 || len(updated) != 0 {
+		t.Errorf("Просмотр не должен менять курсы: %+v, версия %d, события %d", preview, levothyroxine.Version, len(updated))
+	}
+
+	// Без If-Match объединение запрещено
+	if w, _ := send("/v1/reminders/consolidate", "", models.ConsolidationRequest{UserID: "user1"}); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("Ожидался статус 428, получен %d", w.Code)
+	}
+
+	// Курс изменили после просмотра - показанные изменения уже неактуальны
+	if _, err := server.db.SetInventory(ctx, aspirin.ID, 0, models.Inventory{PillsOnHand: 30}); err != nil {
+		t.Fatal(err)
+	}
+	updated = nil
+	w, _ = send("/v1/reminders/consolidate", etag, models.ConsolidationRequest{UserID: "user1"})
+	if w.Code != http.StatusPreconditionFailed || !strings.Contains(w.Body.String(), storage.CodeRemindersChanged) {
+		t.Errorf("Ожидалась ошибка 412 %s, получен %d: %s", storage.CodeRemindersChanged, w.Code, w.Body)
+	}
+	if levothyroxine, _ = server.db.GetScheduleByID(ctx, levothyroxine.ID); levothyroxine.Version != 1 || len(updated) != 0 {
+		t.Errorf("После ошибки курсы не должны меняться: версия %d, события %d", levothyroxine.Version, len(updated))
+	}
+	w, _ = send("/v1/reminders/consolidate/preview", "", models.ConsolidationRequest{UserID: "user1"})
+	if w.Header().Get("ETag") == etag {
+		t.Error("ETag просмотра не изменился после изменения курса")
+	}
+	etag = w.Header().Get("ETag")
+
+	// Объединение сохраняет новые времена, промежуток между левотироксином
+	// и кальцием остается не меньше 4 часов, а курсы, привязанные к еде,
+	// и приостановленные курсы не сдвигаются
+	w, applied := send("/v1/reminders/consolidate", etag, models.ConsolidationRequest{UserID: "user1"})
+	if w.Code != http.StatusOK || !applied.Applied || !reflect.DeepEqual(moments(applied.After), after) {
+		t.Fatalf("Объединение не выполнено: %d %s", w.Code, w.Body)
+	}
+	wantTimes := map[*models.Schedule][]models.TakingTime{
+		levothyroxine: {{Hour: 8}},
+		calcium:       {{Hour: 12}, {Hour: 16, Minute: 30}},
+		aspirin:       {{Hour: 12}, {Hour: 15, Minute: 30}, {Hour: 19}},
+		omega:         {{Hour: 8}, {Hour: 19}},
+		magnesium:     {{Hour: 9}},
+	}
+	for schedule, want := range wantTimes {
+		schedule, _ = server.db.GetScheduleByID(ctx, schedule.ID)
+		if !reflect.DeepEqual(schedule.TakingTimes, want) {
+			t.Errorf("Ожидались приемы %s %v, получены %v", schedule.MedicineName, want, schedule.TakingTimes)
 		}
-		if taking.NextTakingTime.Hour < 8 || taking.NextTakingTime.Hour >= 22 {
-			t.Error("Время приема вне допустимого диапазона (8:00-22:00)")
+	}
+	levothyroxine, _ = server.db.GetScheduleByID(ctx, levothyroxine.ID)
+	omega, _ = server.db.GetScheduleByID(ctx, omega.ID)
+	if levothyroxine.Version != 2 || omega.Version != 1 || len(updated) != 3 {
+		t.Errorf("Ожидалось 3 измененных курса, версии %d и %d, события %d", levothyroxine.Version, omega.Version, len(updated))
+	}
+	takings := server.db.GetNextTakings(ctx, "user1")
+	if len(takings) == 0 || takings[0].NextTakingTime != (models.TakingTime{Hour: 8}) {
+		t.Errorf("Ближайшие приемы не учитывают объединение: %+v", takings)
+	}
+
+	// Повторное объединение уже ничего не меняет
+	if _, again := send("/v1/reminders/consolidate", w.Header().Get("ETag"), models.ConsolidationRequest{UserID: "user1"}); len(again.Changes) != 0 {
+		t.Errorf("Ожидалось, что повторное объединение ничего не сдвинет, получено %+v", again.Changes)
+	}
+
+	if w, _ := send("/v1/reminders/consolidate/preview", "", models.ConsolidationRequest{}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 без user_id, получен %d", w.Code)
+	}
+}
+
+func TestInventoryForecastAndLowStock(t *testing.T) {
+	server := NewServer()
+
+	// Подписываемся на события о заканчивающемся запасе
+	var lowStockEvents []events.Event
+	server.db.Events().Subscribe(func(e events.Event) {
+		if e.Type == events.TypeLowStock {
+			lowStockEvents = append(lowStockEvents, e)
 		}
+	})
+
+	// Создаем расписание с запасом на 4 дня
+	data := models.ScheduleRequest{
+		UserID:       "test123",
+		MedicineName: "Аспирин",
+		Frequency:    3,
+		Duration:     0,
+		Inventory:    &models.Inventory{PillsOnHand: 13, PackSize: 20},
+	}
+	jsonData, _ := json.Marshal(data)
+	req := httptest.NewRequest("POST", "/schedule", bytes.NewBuffer(jsonData))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	var createResponse map[string]string
+	json.NewDecoder(w.Body).Decode(&createResponse)
+	scheduleID := createResponse["schedule_id"]
+
+	// Проверяем прогноз
+	req = httptest.NewRequest("GET", "/schedule/inventory?user_id=test123&schedule_id="+scheduleID, nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+	}
+	var forecast models.InventoryForecast
+	json.NewDecoder(w.Body).Decode(&forecast)
+	if forecast.DailyConsumption != 3 || forecast.DaysLeft != 4 {
+		t.Errorf("Неверный прогноз: %+v", forecast)
+	}
+	if forecast.LowStock {
+		t.Error("Запас еще не должен считаться заканчивающимся")
+	}
+
+	// Отмечаем прием: остается 12 таблеток, запаса хватит на 4 дня
+	dose, _ := json.Marshal(models.DoseRequest{UserID: "test123", ScheduleID: scheduleID})
+	req = httptest.NewRequest("POST", "/schedule/dose", bytes.NewBuffer(dose))
+	req.Header.Set("If-Match", "*")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if len(lowStockEvents) != 0 {
+		t.Error("Событие о заканчивающемся запасе отправлено слишком рано")
+	}
+
+	// Еще один прием: остается 11 таблеток, запаса хватит на 3 дня
+	req = httptest.NewRequest("POST", "/schedule/dose", bytes.NewBuffer(dose))
+	req.Header.Set("If-Match", "*")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	json.NewDecoder(w.Body).Decode(&forecast)
+	if forecast.Inventory.PillsOnHand != 11 || !forecast.LowStock {
+		t.Errorf("Неверный прогноз после приема: %+v", forecast)
+	}
+	if len(lowStockEvents) != 1 {
+		t.Fatalf("Ожидалось 1 событие о заканчивающемся запасе, получено %d", len(lowStockEvents))
+	}
+
+	// Повторно о том же запасе не предупреждаем
+	req = httptest.NewRequest("POST", "/schedule/dose", bytes.NewBuffer(dose))
+	req.Header.Set("If-Match", "*")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if len(lowStockEvents) != 1 {
+		t.Error("Событие о заканчивающемся запасе отправлено повторно")
 	}
+
+	// Пополняем запас одной упаковкой
+	refill, _ := json.Marshal(models.RefillRequest{UserID: "test123", ScheduleID: scheduleID})
+	req = httptest.NewRequest("POST", "/schedule/refill", bytes.NewBuffer(refill))
+	req.Header.Set("If-Match", "*")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	json.NewDecoder(w.Body).Decode(&forecast)
+	if forecast.Inventory.PillsOnHand != 30 || forecast.LowStock {
+		t.Errorf("Неверный прогноз после пополнения: %+v", forecast)
+	}
+
+	// Если запаса мало уже при создании, о нем предупреждает периодическая проверка
+	lowStock := make(chan events.Event, 1)
+	server.db.Events().Subscribe(func(e events.Event) {
+		if e.Type == events.TypeLowStock {
+			lowStock <- e
+		}
+	})
+	short, _, err := server.db.CreateSchedule(context.Background(), &models.ScheduleRequest{
+		UserID:       "test123",
+		MedicineName: "Ибупрофен",
+		Frequency:    3,
+		Inventory:    &models.Inventory{PillsOnHand: 5},
+	})
+	if err != nil {
+		t.Fatalf("Не удалось создать расписание: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.cfg.DueDosesInterval = 10 * time.Millisecond
+	go server.publishDueDoses(ctx)
+	select {
+	case e := <-lowStock:
+		if e.ScheduleID != short.ID {
+			t.Errorf("Ожидалось событие по расписанию %s, получено по %s", short.ID, e.ScheduleID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Не пришло событие о заканчивающемся запасе")
+	}
+
+	// Следующие проверки не предупреждают о том же запасе повторно
+	cancel()
+	if n := server.db.CheckLowStock(context.Background()); n != 0 {
+		t.Errorf("Ожидалось 0 событий о заканчивающемся запасе, получено %d", n)
+	}
+}
+
+func TestPrescriptionsOrder(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	server := NewServerWithClock(config.DefaultConfig(), clk)
+
+	// Два рецепта созданы в одно время, поэтому между ними порядок по ID
+	var want []string
+	var sameTime []string
+	for i := 0; i < 5; i++ {
+		if i != 2 {
+			clk.Add(time.Minute)
+		}
+		prescription, err := server.db.CreatePrescription(context.Background(), &models.PrescriptionRequest{
+			UserID:       "test123",
+			MedicineName: fmt.Sprintf("Лекарство %d", i),
+			Prescriber:   "Иванов И.И.",
+			IssueDate:    start,
+			ValidUntil:   start.AddDate(0, 1, 0),
+		})
+		if err != nil {
+			t.Fatalf("Не удалось создать рецепт: %v", err)
+		}
+		want = append(want, prescription.ID)
+		if i == 1 || i == 2 {
+			sameTime = append(sameTime, prescription.ID)
+		}
+	}
+	if sameTime[0] > sameTime[1] {
+		want[1], want[2] = want[2], want[1]
+	}
+
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest("GET", "/v1/prescriptions?user_id=test123", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		var response map[string][]string
+		json.NewDecoder(w.Body).Decode(&response)
+		if !reflect.DeepEqual(response["prescription_ids"], want) {
+			t.Fatalf("Ожидался порядок %v, получен %v", want, response["prescription_ids"])
+		}
+	}
+}
+
+func TestScheduleFromPrescription(t *testing.T) {
+	server := NewServer()
+
+	// Создаем рецепт, действующий 5 дней, с одной повторной выдачей
+	now := time.Now()
+	prescriptionData := models.PrescriptionRequest{
+		UserID:       "test123",
+		MedicineName: "Амоксициллин",
+		Prescriber:   "Иванов И.И.",
+		IssueDate:    now,
+		ValidUntil:   now.AddDate(0, 0, 5),
+		Refills:      1,
+	}
+	jsonData, _ := json.Marshal(prescriptionData)
+	req := httptest.NewRequest("POST", "/prescription", bytes.NewBuffer(jsonData))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+	}
+	var prescriptionResponse map[string]string
+	json.NewDecoder(w.Body).Decode(&prescriptionResponse)
+	prescriptionID := prescriptionResponse["prescription_id"]
+
+	// Создаем курс на 10 дней по рецепту без названия лекарства
+	data := models.ScheduleRequest{
+		UserID:         "test123",
+		Frequency:      2,
+		Duration:       10,
+		PrescriptionID: prescriptionID,
+	}
+	jsonData, _ = json.Marshal(data)
+	req = httptest.NewRequest("POST", "/schedule", bytes.NewBuffer(jsonData))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d: %s", w.Code, w.Body.String())
+	}
+
+	// Курс длиннее рецепта - должно быть предупреждение
+	var createResponse models.CreateScheduleResponse
+	json.NewDecoder(w.Body).Decode(&createResponse)
+	if len(createResponse.Warnings) != 1 || createResponse.Warnings[0].Code != "course_outlives_prescription" {
+		t.Errorf("Ожидалось предупреждение о сроке рецепта, получено %+v", createResponse.Warnings)
+	}
+
+	schedule, _ := server.db.GetScheduleByID(context.Background(), createResponse.ScheduleID)
+	if schedule.MedicineName != "Амоксициллин" || schedule.PrescriptionID != prescriptionID {
+		t.Errorf("Расписание не связано с рецептом: %+v", schedule)
+	}
+
+	// Используем повторную выдачу дважды - второй раз должна быть ошибка
+	refill, _ := json.Marshal(models.PrescriptionRefillRequest{UserID: "test123", PrescriptionID: prescriptionID})
+	req = httptest.NewRequest("POST", "/prescription/refill", bytes.NewBuffer(refill))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	var prescription models.Prescription
+	json.NewDecoder(w.Body).Decode(&prescription)
+	if prescription.RefillsRemaining != 0 || len(prescription.ScheduleIDs) != 1 {
+		t.Errorf("Неверный рецепт после выдачи: %+v", prescription)
+	}
+
+	req = httptest.NewRequest("POST", "/prescription/refill", bytes.NewBuffer(refill))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Ожидался статус 409, получен %d", w.Code)
+	}
+}
+
+func TestCreateScheduleInteractionWarnings(t *testing.T) {
+	server := NewServer()
+
+	create := func(medicine string) *httptest.ResponseRecorder {
+		data := models.ScheduleRequest{
+			UserID:       "test123",
+			MedicineName: medicine,
+			Frequency:    1,
+			Duration:     30,
+		}
+		jsonData, _ := json.Marshal(data)
+		req := httptest.NewRequest("POST", "/schedule", bytes.NewBuffer(jsonData))
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	create("Варфарин")
+	create("Симвастатин")
+
+	// Аспирин с варфарином - серьезное взаимодействие, но расписание создается
+	w := create("Aspirin 500")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+	}
+	var response models.CreateScheduleResponse
+	json.NewDecoder(w.Body).Decode(&response)
+	if response.ScheduleID == "" {
+		t.Error("Не получен schedule_id")
+	}
+	if len(response.Warnings) != 1 || response.Warnings[0].Severity != "major" {
+		t.Errorf("Ожидалось предупреждение о серьезном взаимодействии, получено %+v", response.Warnings)
+	}
+
+	// Кларитромицин с симвастатином противопоказан - расписание не создается
+	w = create("Клацид")
+	if w.Code != http.StatusConflict {
+		t.Errorf("Ожидался статус 409, получен %d", w.Code)
+	}
+
+	// У другого пользователя нет назначений, поэтому предупреждений нет
+	data := models.ScheduleRequest{UserID: "other", MedicineName: "Клацид", Frequency: 1, Duration: 7}
+	jsonData, _ := json.Marshal(data)
+	req := httptest.NewRequest("POST", "/schedule", bytes.NewBuffer(jsonData))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200, получен %d", w.Code)
+	}
+}
+
+func TestMedicineCatalog(t *testing.T) {
+	server := NewServer()
+
+	// Ищем по синониму в другом регистре
+	req := httptest.NewRequest("GET", "/medicines/search?q="+url.QueryEscape("нурофен"), nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+	}
+	var response map[string][]catalog.Medicine
+	json.NewDecoder(w.Body).Decode(&response)
+	if len(response["medicines"]) != 2 || response["medicines"][0].ID != "ibuprofen-200-tab" {
+		t.Errorf("Неверный результат поиска: %+v", response["medicines"])
+	}
+
+	// Создаем расписание по ID из справочника без названия
+	data := models.ScheduleRequest{
+		UserID:     "test123",
+		MedicineID: "warfarin-2.5-tab",
+		Frequency:  1,
+		Duration:   30,
+	}
+	jsonData, _ := json.Marshal(data)
+	req = httptest.NewRequest("POST", "/schedule", bytes.NewBuffer(jsonData))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	var createResponse models.CreateScheduleResponse
+	json.NewDecoder(w.Body).Decode(&createResponse)
+	schedule, err := server.db.GetScheduleByID(context.Background(), createResponse.ScheduleID)
+	if err != nil || schedule.MedicineName != "Варфарин 2,5 мг" {
+		t.Fatalf("Название не взято из справочника: %+v", schedule)
+	}
+
+	// Взаимодействие находится по действующим веществам из справочника
+	data = models.ScheduleRequest{
+		UserID:     "test123",
+		MedicineID: "cardiomagnyl-75-tab",
+		Frequency:  1,
+		Duration:   30,
+	}
+	jsonData, _ = json.Marshal(data)
+	req = httptest.NewRequest("POST", "/schedule", bytes.NewBuffer(jsonData))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	json.NewDecoder(w.Body).Decode(&createResponse)
+	if len(createResponse.Warnings) != 1 {
+		t.Errorf("Ожидалось предупреждение о взаимодействии, получено %+v", createResponse.Warnings)
+	}
+
+	// Неизвестный ID лекарства
+	data.MedicineID = "unknown"
+	jsonData, _ = json.Marshal(data)
+	req = httptest.NewRequest("POST", "/schedule", bytes.NewBuffer(jsonData))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code == http.StatusOK {
+		t.Error("Расписание с неизвестным лекарством не должно создаваться")
+	}
+}
+
+func TestDailyDoseLimits(t *testing.T) {
+	server := NewServer()
+
+	create := func(medicineID string, frequency int) *httptest.ResponseRecorder {
+		data := models.ScheduleRequest{
+			UserID:     "test123",
+			MedicineID: medicineID,
+			Frequency:  frequency,
+			Duration:   5,
+		}
+		jsonData, _ := json.Marshal(data)
+		req := httptest.NewRequest("POST", "/schedule", bytes.NewBuffer(jsonData))
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Парацетамол 500 мг 4 раза в день и Колдрекс 4 раза в день - ровно 4000 мг
+	if w := create("paracetamol-500-tab", 4); w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+	}
+	if w := create("coldrex-tab", 4); w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d: %s", w.Code, w.Body.String())
+	}
+
+	// Терафлю добавляет еще парацетамол - превышение суточной дозы
+	w := create("theraflu-sachet", 2)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Ожидался статус 422, получен %d", w.Code)
+	}
+
+	// В режиме предупреждений курс создается, но с предупреждением
+	server.cfg.RejectOverDoseLimit = false
+	w = create("theraflu-sachet", 2)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+	}
+	var response models.CreateScheduleResponse
+	json.NewDecoder(w.Body).Decode(&response)
+	if len(response.Warnings) != 1 || response.Warnings[0].Code != "daily_dose_limit" {
+		t.Errorf("Ожидалось предупреждение о суточной дозе, получено %+v", response.Warnings)
+	}
+
+	// Курсы без medicine_id находятся в справочнике по точному названию или синониму
+	named := func(name string, frequency int) []models.Warning {
+		t.Helper()
+		schedule, warnings, err := server.db.CreateSchedule(context.Background(), &models.ScheduleRequest{
+			UserID:       "named",
+			MedicineName: name,
+			Frequency:    frequency,
+			Duration:     5,
+		})
+		if err != nil || schedule == nil {
+			t.Fatalf("Не удалось создать расписание %s: %v", name, err)
+		}
+		return warnings
+	}
+	if warnings := named("Парацетамол 500 мг", 4); len(warnings) != 0 {
+		t.Errorf("Ожидалось отсутствие предупреждений, получено %+v", warnings)
+	}
+	if warnings := named("панадол", 5); len(warnings) != 1 || warnings[0].Code != storage.WarningDailyDoseLimit {
+		t.Errorf("Ожидалось предупреждение о суточной дозе по синониму, получено %+v", warnings)
+	}
+
+	// Название без дозировки не определяет лекарство, поэтому доза не проверена
+	if warnings := named("Ибупрофен", 3); len(warnings) != 1 || warnings[0].Code != storage.WarningDoseLimitUnchecked {
+		t.Errorf("Ожидалось предупреждение о непроверенной дозе, получено %+v", warnings)
+	}
+
+	// Для лекарств без ограниченных веществ предупреждения нет
+	if warnings := named("Омепразол", 1); len(warnings) != 0 {
+		t.Errorf("Ожидалось отсутствие предупреждений, получено %+v", warnings)
+	}
+
+	// Одно вещество в разном написании и в разных единицах суммируется
+	path := filepath.Join(t.TempDir(), "medicines.json")
+	os.WriteFile(path, []byte(`[
+		{"id": "para-mg", "name": "Парацетамол А", "ingredients": [{"name": "Парацетамол", "amount": 500, "unit": "мг"}]},
+		{"id": "para-g", "name": "Парацетамол Б", "ingredients": [{"name": "парацетамол", "amount": 0.5, "unit": "г"}]},
+		{"id": "para-iu", "name": "Парацетамол В", "ingredients": [{"name": "ПАРАЦЕТАМОЛ", "amount": 10, "unit": "МЕ"}]}
+	]`), 0o600)
+	medicines, err := catalog.Load(path)
+	if err != nil {
+		t.Fatalf("Не удалось загрузить справочник: %v", err)
+	}
+	server.db.SetCatalog(medicines)
+	units := func(medicineID string, frequency int) []models.Warning {
+		t.Helper()
+		_, warnings, err := server.db.CreateSchedule(context.Background(), &models.ScheduleRequest{
+			UserID:     "units",
+			MedicineID: medicineID,
+			Frequency:  frequency,
+			Duration:   5,
+		})
+		if err != nil {
+			t.Fatalf("Не удалось создать расписание %s: %v", medicineID, err)
+		}
+		return warnings
+	}
+	units("para-mg", 4)
+	if warnings := units("para-g", 5); len(warnings) != 1 || warnings[0].Code != storage.WarningDailyDoseLimit {
+		t.Errorf("Ожидалось предупреждение о суточной дозе 4500 мг, получено %+v", warnings)
+	}
+
+	// Единицы, которые нельзя перевести в миллиграммы, не пропускаются молча
+	if warnings := units("para-iu", 1); len(warnings) != 1 || warnings[0].Code != storage.WarningDoseLimitUnchecked {
+		t.Errorf("Ожидалось предупреждение о непроверенной дозе, получено %+v", warnings)
+	}
+}
+
+func TestLocalizedErrors(t *testing.T) {
+	server := NewServer()
+
+	createInvalid := func(userID, acceptLanguage string) problem.Problem {
+		data := models.ScheduleRequest{UserID: userID, Frequency: 3, Duration: 7}
+		jsonData, _ := json.Marshal(data)
+		req := httptest.NewRequest("POST", "/schedule", bytes.NewBuffer(jsonData))
+		if acceptLanguage != "" {
+			req.Header.Set("Accept-Language", acceptLanguage)
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		var p problem.Problem
+		json.NewDecoder(w.Body).Decode(&p)
+		return p
+	}
+
+	// По умолчанию сообщения на русском
+	p := createInvalid("test123", "")
+	if p.Detail != "запрос содержит ошибки" || p.Errors[0].Message != "не указано название лекарства" {
+		t.Errorf("Ожидались сообщения на русском: %+v", p)
+	}
+
+	// Язык выбирается по Accept-Language с учетом весов
+	p = createInvalid("test123", "de-DE, en-US;q=0.9, ru;q=0.5")
+	if p.Detail != "request contains errors" || p.Errors[0].Message != "medicine name is required" {
+		t.Errorf("Ожидались сообщения на английском: %+v", p)
+	}
+
+	// Язык из настроек пользователя
+	profile, _ := json.Marshal(models.UserProfile{UserID: "test123", Language: "en"})
+	req := httptest.NewRequest("PUT", "/profile", bytes.NewBuffer(profile))
+	req.Header.Set("If-Match", "*")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/schedule?user_id=test123&schedule_id=unknown", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	json.NewDecoder(w.Body).Decode(&p)
+	if p.Code != "schedule_not_found" || p.Detail != "schedule not found" {
+		t.Errorf("Ожидалось сообщение на английском из настроек: %+v", p)
+	}
+
+	// Уведомления тоже на языке пользователя
+	text := server.notificationText(events.Event{
+		Type:   events.TypeDoseTaken,
+		UserID: "test123",
+		Data:   map[string]any{"medicine_name": "Aspirin"},
+	})
+	if text != "Aspirin dose logged." {
+		t.Errorf("Неверный текст уведомления: %q", text)
+	}
+
+	// Неподдерживаемый язык в настройках
+	profile, _ = json.Marshal(models.UserProfile{UserID: "test123", Language: "fr"})
+	req = httptest.NewRequest("PUT", "/profile", bytes.NewBuffer(profile))
+	req.Header.Set("If-Match", "*")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400, получен %d", w.Code)
+	}
+}
+
+func TestV1ListSchedules(t *testing.T) {
+	server := NewServer()
+
+	// Создаем расписания через /v1
+	medicines := []string{"Витамин С", "Амоксициллин", "Витамин D", "Бисопролол", "Аспирин"}
+	ids := map[string]string{}
+	for _, medicine := range medicines {
+		data := models.ScheduleRequest{UserID: "test123", MedicineName: medicine, Frequency: 1, Duration: 7}
+		jsonData, _ := json.Marshal(data)
+		req := httptest.NewRequest("POST", "/v1/schedule", bytes.NewBuffer(jsonData))
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Header().Get("Deprecation") != "" {
+			t.Error("Маршрут /v1 не должен быть помечен устаревшим")
+		}
+		var response models.CreateScheduleResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		ids[medicine] = response.ScheduleID
+	}
+
+	list := func(query string) models.ScheduleListResponse {
+		req := httptest.NewRequest("GET", "/v1/schedules?user_id=test123&"+query, nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Ожидался статус 200, получен %d: %s", w.Code, w.Body.String())
+		}
+		var response models.ScheduleListResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		return response
+	}
+
+	// Обходим все страницы по 2 расписания, сортируя по названию
+	var names []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("Слишком много страниц")
+		}
+		response := list("sort=medicine_name&limit=2&cursor=" + cursor)
+		for _, schedule := range response.Schedules {
+			names = append(names, schedule.MedicineName)
+		}
+		if response.NextCursor == "" {
+			break
+		}
+		cursor = response.NextCursor
+	}
+	expected := []string{"Амоксициллин", "Аспирин", "Бисопролол", "Витамин D", "Витамин С"}
+	if fmt.Sprint(names) != fmt.Sprint(expected) {
+		t.Errorf("Неверный порядок: %v, ожидался %v", names, expected)
+	}
+
+	// Обратная сортировка
+	response := list("sort=-medicine_name&limit=1")
+	if len(response.Schedules) != 1 || response.Schedules[0].MedicineName != "Витамин С" {
+		t.Errorf("Неверная обратная сортировка: %+v", response.Schedules)
+	}
+
+	// Фильтр по названию лекарства
+	response = list("medicine=" + url.QueryEscape("витамин"))
+	if len(response.Schedules) != 2 {
+		t.Errorf("Ожидалось 2 расписания с витаминами, получено %d", len(response.Schedules))
+	}
+
+	// Приостанавливаем курс и фильтруем по состоянию
+	pause, _ := json.Marshal(models.PauseRequest{UserID: "test123", ScheduleID: ids["Аспирин"]})
+	req := httptest.NewRequest("POST", "/v1/schedule/pause", bytes.NewBuffer(pause))
+	req.Header.Set("If-Match", `"1"`)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+	}
+	response = list("status=paused")
+	if len(response.Schedules) != 1 || response.Schedules[0].ID != ids["Аспирин"] {
+		t.Errorf("Неверный фильтр по состоянию: %+v", response.Schedules)
+	}
+	if response = list("status=active"); len(response.Schedules) != 4 {
+		t.Errorf("Ожидалось 4 активных расписания, получено %d", len(response.Schedules))
+	}
+
+	// Некорректные параметры
+	req = httptest.NewRequest("GET", "/v1/schedules?user_id=test123&sort=dosage", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400, получен %d", w.Code)
+	}
+
+	// Старый маршрут работает и помечен устаревшим
+	req = httptest.NewRequest("GET", "/schedules?user_id=test123", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Deprecation") != "true" {
+		t.Errorf("Старый маршрут: статус %d, Deprecation=%q", w.Code, w.Header().Get("Deprecation"))
+	}
+	if w.Header().Get("Link") != `</v1/schedules>; rel="successor-version"` {
+		t.Errorf("Неверная ссылка на новую версию: %s", w.Header().Get("Link"))
+	}
+	var legacy map[string][]string
+	json.NewDecoder(w.Body).Decode(&legacy)
+	if len(legacy["schedule_ids"]) != 5 {
+		t.Errorf("Ожидалось 5 ID расписаний, получено %d", len(legacy["schedule_ids"]))
+	}
+
+	// Неподдерживаемый метод в /v1
+	req = httptest.NewRequest("DELETE", "/v1/schedule", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Ожидался статус 405, получен %d", w.Code)
+	}
+
+	// Неизвестный маршрут
+	req = httptest.NewRequest("GET", "/v1/unknown", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != problem.ContentType {
+		t.Errorf("Ожидалась ошибка 404 в формате problem+json, получен %d", w.Code)
+	}
+}
+
+func TestCreateScheduleIdempotencyKey(t *testing.T) {
+	cfg := config.DefaultConfig()
+	clk := clock.NewFake(time.Now())
+	server := NewServerWithClock(cfg, clk)
+
+	post := func(key string, request models.ScheduleRequest) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(request)
+		req := httptest.NewRequest("POST", "/v1/schedule", bytes.NewBuffer(body))
+		req.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	request := models.ScheduleRequest{
+		UserID:       "test123",
+		MedicineName: "Аспирин",
+		Frequency:    3,
+		Duration:     7,
+	}
+
+	first := post("key-1", request)
+	if first.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", first.Code)
+	}
+	var created models.CreateScheduleResponse
+	json.NewDecoder(bytes.NewReader(first.Body.Bytes())).Decode(&created)
+
+	// Повтор возвращает тот же ответ и не создает второе расписание
+	retry := post("key-1", request)
+	if retry.Code != http.StatusOK || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("Повтор: статус %d, Idempotent-Replayed=%q", retry.Code, retry.Header().Get("Idempotent-Replayed"))
+	}
+	if retry.Body.String() != first.Body.String() {
+		t.Errorf("Ответ на повтор отличается: %s != %s", retry.Body.String(), first.Body.String())
+	}
+	// ID запроса у повтора свой, а не сохраненный от первого запроса
+	if id := retry.Header().Values(headerRequestID); len(id) != 1 || id[0] == first.Header().Get(headerRequestID) {
+		t.Errorf("Повтор вернул X-Request-ID первого запроса: %v", id)
+	}
+	if schedules := server.db.GetSchedulesByUserID(context.Background(), "test123"); len(schedules) != 1 {
+		t.Errorf("Ожидалось 1 расписание, получено %d", len(schedules))
+	}
+
+	// Устаревший маршрут - та же операция, поэтому ключ общий
+	body, _ := json.Marshal(request)
+	req := httptest.NewRequest("POST", "/schedule", bytes.NewBuffer(body))
+	req.Header.Set("Idempotency-Key", "key-1")
+	legacy := httptest.NewRecorder()
+	server.router.ServeHTTP(legacy, req)
+	if legacy.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Ожидался сохраненный ответ на устаревшем маршруте, получен статус %d", legacy.Code)
+	}
+
+	// Тот же ключ другого пользователя не получает чужой ответ
+	other := request
+	other.UserID = "other"
+	if w := post("key-1", other); w.Code != http.StatusOK || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("Ключ другого пользователя: статус %d, Idempotent-Replayed=%q", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+	if schedules := server.db.GetSchedulesByUserID(context.Background(), "other"); len(schedules) != 1 {
+		t.Errorf("Ожидалось 1 расписание другого пользователя, получено %d", len(schedules))
+	}
+
+	// Тот же ключ с другим телом
+	request.Frequency = 2
+	w := post("key-1", request)
+	if w.Code != http.StatusUnprocessableEntity || w.Header().Get("Content-Type") != problem.ContentType {
+		t.Errorf("Ожидалась ошибка 422 в формате problem+json, получен %d", w.Code)
+	}
+
+	// Новый ключ создает новое расписание
+	if w = post("key-2", request); w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+	}
+	if schedules := server.db.GetSchedulesByUserID(context.Background(), "test123"); len(schedules) != 2 {
+		t.Errorf("Ожидалось 2 расписания, получено %d", len(schedules))
+	}
+
+	// Через IdempotencyTTL по часам сервера ключ можно использовать снова
+	clk.Add(cfg.IdempotencyTTL)
+	if w = post("key-1", request); w.Code != http.StatusOK || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("Ключ после истечения срока: статус %d, Idempotent-Replayed=%q", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+}
+
+func TestScheduleETags(t *testing.T) {
+	server := NewServer()
+	schedule, _, err := server.db.CreateSchedule(context.Background(), &models.ScheduleRequest{
+		UserID:       "test123",
+		MedicineName: "Аспирин",
+		Frequency:    3,
+		Duration:     7,
+	})
+	if err != nil {
+		t.Fatalf("Не удалось создать расписание: %v", err)
+	}
+	scheduleID := schedule.ID
+
+	get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	mutate := func(path, ifMatch string, request any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(request)
+		req := httptest.NewRequest("PUT", path, bytes.NewBuffer(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	detailsPath := "/v1/schedule?user_id=test123&schedule_id=" + scheduleID
+	w := get(detailsPath, "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag != `"1"` {
+		t.Fatalf("Ожидался статус 200 и ETag \"1\", получен %d и %s", w.Code, etag)
+	}
+	if w = get(detailsPath, etag); w.Code != http.StatusNotModified {
+		t.Errorf("Ожидался статус 304, получен %d", w.Code)
+	}
+
+	inventory := models.InventoryRequest{
+		UserID:     "test123",
+		ScheduleID: scheduleID,
+		Inventory:  models.Inventory{PillsOnHand: 30},
+	}
+
+	// Без If-Match изменение в /v1 запрещено
+	if w = mutate("/v1/schedule/inventory", "", inventory); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("Ожидался статус 428, получен %d", w.Code)
+	}
+
+	// Изменение с актуальной версией проходит и меняет ETag
+	w = mutate("/v1/schedule/inventory", etag, inventory)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Fatalf("Ожидался статус 200 и ETag \"2\", получен %d и %s", w.Code, w.Header().Get("ETag"))
+	}
+
+	// Второе устройство с устаревшей версией получает 412
+	w = mutate("/v1/schedule/inventory", etag, inventory)
+	if w.Code != http.StatusPreconditionFailed || w.Header().Get("Content-Type") != problem.ContentType {
+		t.Errorf("Ожидалась ошибка 412 в формате problem+json, получен %d", w.Code)
+	}
+	if w = get(detailsPath, etag); w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200 после изменения, получен %d", w.Code)
+	}
+
+	// Устаревшие маршруты тоже требуют If-Match и отклоняют устаревшую версию
+	if w = mutate("/schedule/inventory", "", inventory); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("Ожидался статус 428, получен %d", w.Code)
+	}
+	if w = mutate("/schedule/inventory", etag, inventory); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Ожидался статус 412, получен %d", w.Code)
+	}
+	if w = mutate("/schedule/inventory", "*", inventory); w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200, получен %d", w.Code)
+	}
+
+	// Прием отмечается и без If-Match, но устаревшая версия отклоняется
+	logDose := func(ifMatch, idempotencyKey string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.DoseRequest{UserID: "test123", ScheduleID: scheduleID})
+		req := httptest.NewRequest("POST", "/v1/schedule/dose", bytes.NewBuffer(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		if idempotencyKey != "" {
+			req.Header.Set(headerIdempotencyKey, idempotencyKey)
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	if w = logDose("", ""); w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200 без If-Match, получен %d: %s", w.Code, w.Body)
+	}
+	if w = logDose(etag, ""); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Ожидался статус 412 для устаревшей версии, получен %d", w.Code)
+	}
+
+	// Повтор с тем же Idempotency-Key не списывает таблетку второй раз
+	for i := 0; i < 2; i++ {
+		if w = logDose("", "dose-1"); w.Code != http.StatusOK {
+			t.Fatalf("Ожидался статус 200, получен %d: %s", w.Code, w.Body)
+		}
+	}
+	if reloaded, _ := server.db.GetScheduleByID(context.Background(), scheduleID); reloaded.Inventory.PillsOnHand != 28 {
+		t.Errorf("Ожидалось 28 таблеток после двух приемов, осталось %d", reloaded.Inventory.PillsOnHand)
+	}
+
+	// Список ближайших приемов поддерживает If-None-Match
+	w = get("/v1/next_takings?user_id=test123", "")
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == "" {
+		t.Fatalf("Ожидался статус 200 с ETag, получен %d", w.Code)
+	}
+	if w = get("/v1/next_takings?user_id=test123", w.Header().Get("ETag")); w.Code != http.StatusNotModified {
+		t.Errorf("Ожидался статус 304, получен %d", w.Code)
+	}
+}
+
+// Запускать с -race: чтение расписаний и рецептов не должно пересекаться
+// с их изменением
+func TestConcurrentScheduleAccess(t *testing.T) {
+	server := NewServer()
+	ctx := context.Background()
+	schedule, _, err := server.db.CreateSchedule(ctx, &models.ScheduleRequest{
+		UserID:       "test123",
+		MedicineName: "Аспирин",
+		Frequency:    3,
+		Inventory:    &models.Inventory{PillsOnHand: 1000},
+	})
+	if err != nil {
+		t.Fatalf("Не удалось создать расписание: %v", err)
+	}
+	now := time.Now()
+	prescription, err := server.db.CreatePrescription(ctx, &models.PrescriptionRequest{
+		UserID:       "test123",
+		MedicineName: "Парацетамол 500 мг",
+		Prescriber:   "Иванов И.И.",
+		IssueDate:    now,
+		ValidUntil:   now.AddDate(0, 1, 0),
+		Refills:      100,
+	})
+	if err != nil {
+		t.Fatalf("Не удалось создать рецепт: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			server.db.SetPaused(ctx, schedule.ID, 0, i%2 == 0)
+			server.db.LogDose(ctx, schedule.ID, 0)
+			server.db.UsePrescriptionRefill(ctx, prescription.ID)
+			if i%10 == 0 {
+				server.db.CreateSchedule(ctx, &models.ScheduleRequest{UserID: "test123", PrescriptionID: prescription.ID, Frequency: 1, Duration: 5})
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			got, _ := server.db.GetScheduleByID(ctx, schedule.ID)
+			page, _, _ := server.db.ListSchedules(ctx, "test123", storage.ListOptions{})
+			saved, _ := server.db.GetPrescriptionByID(ctx, prescription.ID)
+			json.Marshal(got)
+			json.Marshal(page)
+			json.Marshal(saved)
+		}
+	}()
+	wg.Wait()
+}
+
+// Контрактный тест: каждая операция из openapi.yaml вызывается с проверкой
+// запросов и ответов по спецификации, поэтому расхождение кода и описания
+// API приводит к падению теста
+func TestOpenAPIContract(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.ValidateRequests = true
+	cfg.ValidateResponses = true
+	cfg.AdminAPIKey = "admin-secret"
+	cfg.UserAPIKeys = map[string]string{"contract-key": "contract"}
+	server := NewServerWithConfig(cfg)
+	if server.spec == nil {
+		t.Fatal("Не удалось загрузить спецификацию API")
+	}
+
+	covered := make(map[string]bool)
+	etag := ""
+	apiKey := ""
+	call := func(method, path string, body any, status int) *httptest.ResponseRecorder {
+		t.Helper()
+		var reader *bytes.Buffer
+		if body != nil {
+			data, _ := json.Marshal(body)
+			reader = bytes.NewBuffer(data)
+		} else {
+			reader = &bytes.Buffer{}
+		}
+		req := httptest.NewRequest(method, path, reader)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if etag != "" {
+			req.Header.Set("If-Match", etag)
+		}
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		if operation, ok := server.spec.Find(req); ok {
+			covered[operation.String()] = true
+		}
+
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Errorf("%s %s: ожидался статус %d, получен %d: %s", method, path, status, w.Code, w.Body.String())
+		}
+		if tag := w.Header().Get("ETag"); tag != "" && w.Code == http.StatusOK {
+			etag = tag
+		}
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder, v any) {
+		t.Helper()
+		if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+			t.Fatalf("Не удалось прочитать ответ: %v", err)
+		}
+	}
+
+	user := "contract"
+	call("PUT", "/v1/profile", models.UserProfile{UserID: user, Language: "en"}, http.StatusPreconditionRequired)
+	etag = "*"
+	call("PUT", "/v1/profile", models.UserProfile{UserID: user, Language: "en"}, http.StatusOK)
+	call("GET", "/v1/profile?user_id="+user, nil, http.StatusOK)
+
+	// Объединение напоминаний
+	call("POST", "/v1/reminders/consolidate/preview", models.ConsolidationRequest{UserID: user}, http.StatusOK)
+	call("POST", "/v1/reminders/consolidate", models.ConsolidationRequest{UserID: user}, http.StatusOK)
+
+	// Рецепт
+	now := time.Now().UTC()
+	var created map[string]string
+	decode(call("POST", "/v1/prescription", models.PrescriptionRequest{
+		UserID:       user,
+		MedicineName: "Парацетамол 500 мг",
+		Prescriber:   "Иванов И.И.",
+		IssueDate:    now,
+		ValidUntil:   now.AddDate(0, 1, 0),
+		Refills:      1,
+	}, http.StatusOK), &created)
+	prescriptionID := created["prescription_id"]
+	call("GET", "/v1/prescription?user_id="+user+"&prescription_id="+prescriptionID, nil, http.StatusOK)
+	call("GET", "/v1/prescriptions?user_id="+user, nil, http.StatusOK)
+	call("POST", "/v1/prescription/refill", models.PrescriptionRefillRequest{UserID: user, PrescriptionID: prescriptionID}, http.StatusOK)
+
+	// Справочник
+	var found map[string][]catalog.Medicine
+	decode(call("GET", "/v1/medicines/search?q="+url.QueryEscape("парацетамол"), nil, http.StatusOK), &found)
+	if len(found["medicines"]) == 0 {
+		t.Fatal("Поиск в справочнике ничего не нашел")
+	}
+	call("GET", "/v1/medicine?medicine_id="+found["medicines"][0].ID, nil, http.StatusOK)
+
+	// Просмотр расписания перед созданием
+	call("POST", "/v1/schedule/preview", models.ScheduleRequest{UserID: user, MedicineName: "Аспирин", Frequency: 3, Duration: 5}, http.StatusOK)
+
+	// Постоянный курс (duration 0) по рецепту
+	var schedule models.CreateScheduleResponse
+	decode(call("POST", "/v1/schedule", models.ScheduleRequest{
+		UserID:         user,
+		Frequency:      2,
+		Duration:       0,
+		PrescriptionID: prescriptionID,
+		Inventory:      &models.Inventory{PillsOnHand: 20, PackSize: 10},
+	}, http.StatusOK), &schedule)
+	scheduleQuery := "?user_id=" + user + "&schedule_id=" + schedule.ScheduleID
+
+	call("GET", "/v1/schedule"+scheduleQuery, nil, http.StatusOK)
+	call("GET", "/v1/schedules?user_id="+user+"&sort=-medicine_name&limit=5", nil, http.StatusOK)
+	call("GET", "/v1/next_takings?user_id="+user, nil, http.StatusOK)
+	call("GET", "/v1/schedule/inventory"+scheduleQuery, nil, http.StatusOK)
+
+	reference := models.DoseRequest{UserID: user, ScheduleID: schedule.ScheduleID}
+	call("PUT", "/v1/schedule/inventory", models.InventoryRequest{
+		UserID:     user,
+		ScheduleID: schedule.ScheduleID,
+		Inventory:  models.Inventory{PillsOnHand: 5, PackSize: 10},
+	}, http.StatusOK)
+	call("POST", "/v1/schedule/refill", models.RefillRequest{UserID: user, ScheduleID: schedule.ScheduleID, Packs: 2}, http.StatusOK)
+	call("POST", "/v1/schedule/dose", reference, http.StatusOK)
+	call("POST", "/v1/schedule/pause", reference, http.StatusOK)
+	call("POST", "/v1/schedule/resume", reference, http.StatusOK)
+	call("POST", "/v1/interactions/reload", nil, http.StatusForbidden)
+	apiKey = cfg.AdminAPIKey
+	call("POST", "/v1/interactions/reload", nil, http.StatusNoContent)
+	apiKey = ""
+	call("GET", "/schedules?user_id="+user, nil, http.StatusOK)
+
+	// Ошибки тоже проверяются по спецификации
+	etag = ""
+	call("GET", "/v1/schedule?user_id="+user+"&schedule_id=unknown", nil, http.StatusNotFound)
+	call("PUT", "/v1/schedule/inventory", models.InventoryRequest{UserID: user, ScheduleID: schedule.ScheduleID}, http.StatusPreconditionRequired)
+	w := call("POST", "/v1/schedule", models.ScheduleRequest{UserID: user, MedicineName: "Аспирин", Frequency: 0}, http.StatusBadRequest)
+	if !bytes.Contains(w.Body.Bytes(), []byte(problem.CodeInvalidRequest)) {
+		t.Errorf("Ожидалась ошибка %s, получено: %s", problem.CodeInvalidRequest, w.Body.String())
+	}
+
+	// Поток событий: с отмененным контекстом обработчик сразу завершается
+	streamCtx, stop := context.WithCancel(context.Background())
+	stop()
+	req := httptest.NewRequest("GET", "/v1/events", nil).WithContext(streamCtx)
+	req.Header.Set("X-API-Key", "contract-key")
+	if operation, ok := server.spec.Find(req); ok {
+		covered[operation.String()] = true
+	}
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Ожидался поток событий, получен статус %d", w.Code)
+	}
+
+	for _, operation := range server.spec.Operations() {
+		if !covered[operation.String()] {
+			t.Errorf("Операция %s из спецификации не проверена", operation)
+		}
+	}
+
+	// И наоборот: каждый маршрут роутера описан в спецификации. Устаревшие
+	// маршруты без версии ищутся среди маршрутов /v1.
+	service := map[string]bool{"/metrics": true, "/healthz": true, "/readyz": true, "/openapi.yaml": true}
+	err := server.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || service[path] {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			t.Errorf("У маршрута %s не указан метод", path)
+			return nil
+		}
+		for _, method := range methods {
+			if _, ok := server.spec.Find(httptest.NewRequest(method, path, nil)); !ok {
+				t.Errorf("Маршрут %s %s не описан в спецификации", method, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGRPCAPI(t *testing.T) {
+	server := NewServerWithClock(config.DefaultConfig(), clock.NewFake(time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC)))
+
+	// gRPC сервер в памяти поверх того же хранилища
+	listener := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	grpcapi.NewServer(server.db, server.logger).Register(g)
+	go g.Serve(listener)
+	defer g.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Не удалось подключиться к gRPC серверу: %v", err)
+	}
+	defer conn.Close()
+	client := pillpb.NewScheduleServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	created, err := client.CreateSchedule(ctx, &pillpb.CreateScheduleRequest{
+		UserId:       "test123",
+		MedicineName: "Аспирин",
+		Frequency:    1,
+		Duration:     7,
+	})
+	if err != nil {
+		t.Fatalf("Не удалось создать расписание: %v", err)
+	}
+	scheduleID := created.GetSchedule().GetId()
+
+	// Расписание, созданное через gRPC, доступно и через REST
+	req := httptest.NewRequest("GET", "/v1/schedule?user_id=test123&schedule_id="+scheduleID, nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200, получен %d", w.Code)
+	}
+
+	schedule, err := client.GetSchedule(ctx, &pillpb.GetScheduleRequest{UserId: "test123", ScheduleId: scheduleID})
+	if err != nil || schedule.GetMedicineName() != "Аспирин" || len(schedule.GetTakingTimes()) != 1 {
+		t.Errorf("Неверное расписание: %v, ошибка: %v", schedule, err)
+	}
+	if _, err := client.GetSchedule(ctx, &pillpb.GetScheduleRequest{UserId: "other", ScheduleId: scheduleID}); status.Code(err) != codes.NotFound {
+		t.Errorf("Ожидался код NotFound, получен %v", status.Code(err))
+	}
+	if _, err := client.CreateSchedule(ctx, &pillpb.CreateScheduleRequest{UserId: "test123"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Ожидался код InvalidArgument, получен %v", status.Code(err))
+	}
+
+	// Распределение приемов и приемы относительно еды доступны и через gRPC
+	spread, err := client.CreateSchedule(ctx, &pillpb.CreateScheduleRequest{
+		UserId:       "grpc-meals",
+		MedicineName: "Парацетамол",
+		Frequency:    3,
+		Distribution: "front_loaded",
+	})
+	if err != nil || spread.GetSchedule().GetDistribution() != "front_loaded" {
+		t.Errorf("Ожидалось распределение front_loaded, получено %v, ошибка: %v", spread, err)
+	}
+	meals, err := client.CreateSchedule(ctx, &pillpb.CreateScheduleRequest{
+		UserId:       "grpc-meals",
+		MedicineName: "Омепразол",
+		MealDoses: []*pillpb.MealDose{
+			{Meal: "breakfast", Relation: "before", OffsetMinutes: 30},
+			{Meal: "dinner", Relation: "after"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Не удалось создать расписание относительно еды: %v", err)
+	}
+	doses := meals.GetSchedule().GetMealDoses()
+	if len(doses) != 2 || doses[0].GetOffsetMinutes() != 30 || doses[1].GetRelation() != "after" || len(meals.GetSchedule().GetTakingTimes()) != 2 {
+		t.Errorf("Неверные приемы относительно еды: %v", meals.GetSchedule())
+	}
+
+	list, err := client.ListSchedules(ctx, &pillpb.ListSchedulesRequest{UserId: "test123"})
+	if err != nil || len(list.GetSchedules()) != 1 {
+		t.Errorf("Ожидалось 1 расписание, получено %v, ошибка: %v", list, err)
+	}
+	if _, err := client.GetNextTakings(ctx, &pillpb.GetNextTakingsRequest{UserId: "test123"}); err != nil {
+		t.Errorf("Не удалось получить ближайшие приемы: %v", err)
+	}
+
+	// Поток начинается с текущего списка приемов
+	stream, err := client.WatchNextTakings(ctx, &pillpb.WatchNextTakingsRequest{UserId: "test123"})
+	if err != nil {
+		t.Fatalf("Не удалось подписаться на приемы: %v", err)
+	}
+	update, err := stream.Recv()
+	if err != nil || update.GetReason() != "snapshot" {
+		t.Fatalf("Ожидался снимок приемов, получено %v, ошибка: %v", update, err)
+	}
+
+	// Наступает время приема в 9:00 завтра
+	from := time.Date(2024, 5, 2, 8, 59, 0, 0, time.UTC)
+	if n := server.db.PublishDueDoses(context.Background(), from, from.Add(2*time.Minute)); n != 1 {
+		t.Fatalf("Ожидалось 1 событие о приеме, получено %d", n)
+	}
+	update, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("Не удалось получить изменение приемов: %v", err)
+	}
+	if update.GetReason() != events.TypeDoseDue || update.GetDue().GetScheduleId() != scheduleID ||
+		update.GetDue().GetNextTakingTime().GetHour() != 9 {
+		t.Errorf("Неверное событие о приеме: %v", update)
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.UserAPIKeys = map[string]string{"key-test123": "test123"}
+	cfg.AdminAPIKey = "admin-secret"
+	server := NewServerWithClock(cfg, clock.NewFake(time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC)))
+	ts := httptest.NewServer(server.router)
+	defer ts.Close()
+
+	// Поток открывается только для пользователя ключа API
+	for _, tt := range []struct {
+		name, path, apiKey string
+		status             int
+	}{
+		{"без ключа", "/v1/events?user_id=test123", "", http.StatusUnauthorized},
+		{"неизвестный ключ", "/v1/events?user_id=test123", "client-a", http.StatusUnauthorized},
+		{"чужой пользователь", "/v1/events?user_id=other", "key-test123", http.StatusForbidden},
+		{"администратор без user_id", "/v1/events", "admin-secret", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.apiKey != "" {
+			req.Header.Set("X-API-Key", tt.apiKey)
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s: ожидался статус %d, получен %d", tt.name, tt.status, w.Code)
+		}
+	}
+
+	// connect открывает поток событий и возвращает читатель событий
+	connect := func(lastEventID string) (func() (string, string), func()) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/v1/events", nil)
+		req.Header.Set("X-API-Key", "key-test123")
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Не удалось подключиться к потоку событий: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("Ожидался поток событий, получен статус %d", resp.StatusCode)
+		}
+		reader := bufio.NewReader(resp.Body)
+
+		// next возвращает ID и тип следующего события
+		next := func() (string, string) {
+			var id, eventType string
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					t.Fatalf("Поток событий прервался: %v", err)
+				}
+				line = strings.TrimRight(line, "\n")
+				switch {
+				case strings.HasPrefix(line, "id: "):
+					id = strings.TrimPrefix(line, "id: ")
+				case strings.HasPrefix(line, "event: "):
+					eventType = strings.TrimPrefix(line, "event: ")
+				case line == "" && eventType != "":
+					return id, eventType
+				}
+			}
+		}
+		return next, func() {
+			cancel()
+			resp.Body.Close()
+		}
+	}
+
+	schedule, _, _ := server.db.CreateSchedule(context.Background(), &models.ScheduleRequest{
+		UserID:       "test123",
+		MedicineName: "Аспирин",
+		Frequency:    1,
+		Duration:     7,
+	})
+
+	// Новые события приходят сразу, события других пользователей не видны
+	next, disconnect := connect("")
+	server.db.CreateSchedule(context.Background(), &models.ScheduleRequest{UserID: "other", MedicineName: "Аспирин", Frequency: 1, Duration: 7})
+	if _, err := server.db.LogDose(context.Background(), schedule.ID, 0); err != nil {
+		t.Fatalf("Не удалось отметить прием: %v", err)
+	}
+	lastID, eventType := next()
+	if eventType != events.TypeDoseTaken {
+		t.Fatalf("Ожидалось событие %s, получено %s", events.TypeDoseTaken, eventType)
+	}
+	disconnect()
+
+	// Пока клиент отключен, наступает время приема
+	from := time.Date(2024, 5, 2, 8, 59, 0, 0, time.UTC)
+	server.db.PublishDueDoses(context.Background(), from, from.Add(2*time.Minute))
+
+	// После переподключения с Last-Event-ID приходит пропущенное событие
+	next, disconnect = connect(lastID)
+	if _, eventType = next(); eventType != events.TypeDoseDue {
+		t.Errorf("Ожидалось событие %s, получено %s", events.TypeDoseDue, eventType)
+	}
+	disconnect()
+
+	// Если пропущенные события не сохранились, клиент получает reset
+	next, disconnect = connect("1000000")
+	if _, eventType = next(); eventType != "reset" {
+		t.Errorf("Ожидалось событие reset, получено %s", eventType)
+	}
+	disconnect()
+}
+
+func TestDueDosesSkipInactiveCourses(t *testing.T) {
+	server := NewServerWithClock(config.DefaultConfig(), clock.NewFake(time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC)))
+	ctx := context.Background()
+
+	create := func(userID string, duration int) *models.Schedule {
+		schedule, _, err := server.db.CreateSchedule(ctx, &models.ScheduleRequest{
+			UserID:       userID,
+			MedicineName: "Аспирин",
+			Frequency:    1,
+			Duration:     duration,
+		})
+		if err != nil {
+			t.Fatalf("Не удалось создать расписание: %v", err)
+		}
+		return schedule
+	}
+	active := create("active", 7)
+	paused := create("paused", 7)
+	create("short", 1)
+	if _, err := server.db.SetPaused(ctx, paused.ID, 0, true); err != nil {
+		t.Fatalf("Не удалось приостановить курс: %v", err)
+	}
+
+	var due []events.Event
+	server.db.Events().Subscribe(func(e events.Event) {
+		if e.Type == events.TypeDoseDue {
+			due = append(due, e)
+		}
+	})
+
+	// Через три дня короткий курс уже закончился, а приостановленный не напоминает.
+	// Промежуток захватывает полночь и утро следующего дня.
+	from := time.Date(2024, 5, 3, 23, 0, 0, 0, time.UTC)
+	if n := server.db.PublishDueDoses(ctx, from, from.Add(11*time.Hour)); n != 1 {
+		t.Fatalf("Ожидалось 1 событие о приеме, получено %d", n)
+	}
+	if len(due) != 1 || due[0].ScheduleID != active.ID || due[0].Time.Hour() != 9 {
+		t.Errorf("Ожидался прием в 9:00 по курсу %s, получено %v", active.ID, due)
+	}
+
+	// Приемы до начала промежутка не публикуются повторно
+	if n := server.db.PublishDueDoses(ctx, from.Add(11*time.Hour), from.Add(12*time.Hour)); n != 0 {
+		t.Errorf("Ожидалось 0 событий о приеме, получено %d", n)
+	}
+}
+
+func TestLogsRedactUserData(t *testing.T) {
+	server := NewServer()
+	var output bytes.Buffer
+	logger, err := logging.New(&output, logging.Options{Level: "info", Format: logging.FormatJSON, HashKey: "test"})
+	if err != nil {
+		t.Fatalf("Не удалось создать лог: %v", err)
+	}
+	server.logger = logger
+
+	createData := models.ScheduleRequest{
+		UserID:       "test123",
+		MedicineName: "Аспирин",
+		Frequency:    3,
+		Duration:     7,
+	}
+	jsonData, _ := json.Marshal(createData)
+	req := httptest.NewRequest("POST", "/schedule", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	var createResponse map[string]string
+	json.NewDecoder(w.Body).Decode(&createResponse)
+
+	req = httptest.NewRequest("GET", "/schedule?user_id=test123&schedule_id="+createResponse["schedule_id"], nil)
+	req.Header.Set("X-Request-ID", "req-42")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Header().Get("X-Request-ID") != "req-42" {
+		t.Errorf("Ожидался ID запроса req-42, получен %q", w.Header().Get("X-Request-ID"))
+	}
+
+	// В логах уровня info нет ID пользователя и названия лекарства
+	logs := output.String()
+	for _, secret := range []string{"test123", "Аспирин"} {
+		if strings.Contains(logs, secret) {
+			t.Errorf("Лог содержит скрытые данные %q:\n%s", secret, logs)
+		}
+	}
+
+	// Запись о запросе содержит ID запроса и хеш пользователя
+	hash := logging.HashUserID([]byte("test"), "test123")
+	found := false
+	for _, line := range strings.Split(strings.TrimSpace(logs), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Запись лога не в формате JSON: %s", line)
+		}
+		if record["request_id"] == "req-42" && record["user_hash"] == hash && record["status"] == float64(http.StatusOK) {
+			found = true
+		}
+		// Данные событий могут содержать любые поля, поэтому пишутся только в отладочный лог
+		if _, ok := record["data"]; ok {
+			t.Errorf("Данные события попали в лог уровня info: %s", line)
+		}
+	}
+	if !found {
+		t.Errorf("Не найдена запись о запросе с ID запроса и хешем пользователя:\n%s", logs)
+	}
+}
+
+func TestInvalidResponseLogHidesValues(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.ValidateResponses = true
+	server := NewServerWithConfig(cfg)
+	if server.spec == nil {
+		t.Fatal("Спецификация API не загружена")
+	}
+	var output bytes.Buffer
+	logger, err := logging.New(&output, logging.Options{Level: "info", Format: logging.FormatJSON})
+	if err != nil {
+		t.Fatalf("Не удалось создать лог: %v", err)
+	}
+	server.logger = logger
+
+	// Ответ без обязательных полей расписания, но с названием лекарства
+	invalid := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"medicine_name":"Аспирин"}`))
+	})
+	req := httptest.NewRequest("GET", "/v1/schedule?user_id=test123&schedule_id=1", nil)
+	w := httptest.NewRecorder()
+	server.logRequests(server.validateSpec(invalid)).ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Ожидался статус 500, получен %d", w.Code)
+	}
+	logs := output.String()
+	if !strings.Contains(logs, "ответ не соответствует спецификации") || strings.Contains(logs, "Аспирин") {
+		t.Errorf("Ожидалась ошибка проверки без значения из ответа:\n%s", logs)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	server := NewServer()
+
+	createData := models.ScheduleRequest{
+		UserID:       "test123",
+		MedicineName: "Аспирин",
+		Frequency:    3,
+		Duration:     7,
+	}
+	jsonData, _ := json.Marshal(createData)
+	req := httptest.NewRequest("POST", "/v1/schedule", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	// Разные пользователи попадают в один ряд метрик маршрута
+	for _, userID := range []string{"test123", "other"} {
+		req = httptest.NewRequest("GET", "/v1/next_takings?user_id="+userID, nil)
+		server.router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req = httptest.NewRequest("GET", "/metrics", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+	}
+
+	body := w.Body.String()
+	for _, expected := range []string{
+		`takeapill_http_requests_total{method="POST",route="/v1/schedule",status="200"} 1`,
+		`takeapill_http_requests_total{method="GET",route="/v1/next_takings",status="200"} 2`,
+		`takeapill_http_request_duration_seconds_count{method="GET",route="/v1/next_takings"} 2`,
+		`takeapill_next_takings_duration_seconds_count 2`,
+		`takeapill_schedules{state="active"} 1`,
+		`takeapill_users 1`,
+		`takeapill_active_users 1`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("В метриках нет строки %q", expected)
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	if _, err := tracing.Setup(context.Background(), tracing.Options{HashKey: "test"}); err != nil {
+		t.Fatalf("Не удалось настроить трассировку: %v", err)
+	}
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	server := NewServer()
+	server.db.CreateSchedule(context.Background(), &models.ScheduleRequest{
+		UserID:       "test123",
+		MedicineName: "Аспирин",
+		Frequency:    3,
+		Duration:     7,
+	})
+
+	// Трассировка продолжается с контекста клиента
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest("GET", "/v1/next_takings?user_id=test123", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", w.Code)
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID().String() == traceID {
+			spans[span.Name()] = span
+		}
+	}
+
+	request, ok := spans["/v1/next_takings"]
+	if !ok {
+		t.Fatalf("Нет спана запроса в трассировке клиента, есть: %v", spans)
+	}
+	storageSpan, ok := spans["storage.GetNextTakings"]
+	if !ok {
+		t.Fatal("Нет спана хранилища")
+	}
+	if storageSpan.Parent().SpanID() != request.SpanContext().SpanID() {
+		t.Error("Спан хранилища должен быть вложен в спан запроса")
+	}
+	if _, ok := spans["next_takings.encode"]; !ok {
+		t.Error("Нет спана кодирования ответа")
+	}
+
+	// Вместо ID пользователя в атрибутах его хеш
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range append(request.Attributes(), storageSpan.Attributes()...) {
+		if kv.Value.AsString() == "test123" {
+			t.Errorf("Атрибут %s содержит ID пользователя", kv.Key)
+		}
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["user.hash"].AsString() != logging.HashUserID([]byte("test"), "test123") {
+		t.Errorf("Неверный хеш пользователя: %q", attrs["user.hash"].AsString())
+	}
+	if attrs["schedule.count"].AsInt64() != 1 {
+		t.Errorf("Ожидалось 1 расписание, получено %d", attrs["schedule.count"].AsInt64())
+	}
+	if attrs["http.route"].AsString() != "/v1/next_takings" {
+		t.Errorf("Неверный маршрут: %q", attrs["http.route"].AsString())
+	}
+}
+
+func TestHealthAndGracefulShutdown(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.ShutdownTimeout = 5 * time.Second
+	cfg.ShutdownDelay = 300 * time.Millisecond
+	cfg.UserAPIKeys = map[string]string{"key-test123": "test123"}
+	server := NewServerWithConfig(cfg)
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: ожидался статус 200, получен %d", path, w.Code)
+		}
+	}
+
+	// Медленный запрос, который должен успеть завершиться при остановке
+	started := make(chan struct{})
+	server.router.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+
+	httpListener, _ := net.Listen("tcp", "127.0.0.1:0")
+	grpcListener, _ := net.Listen("tcp", "127.0.0.1:0")
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.serve(ctx, httpListener, grpcListener)
+	}()
+	baseURL := "http://" + httpListener.Addr().String()
+
+	// Открытый поток событий не мешает остановке
+	streamReq, _ := http.NewRequest("GET", baseURL+"/v1/events", nil)
+	streamReq.Header.Set("X-API-Key", "key-test123")
+	stream, err := http.DefaultClient.Do(streamReq)
+	if err != nil {
+		t.Fatalf("Не удалось подключиться к потоку событий: %v", err)
+	}
+	defer stream.Body.Close()
+
+	slow := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(baseURL + "/slow")
+		if err != nil {
+			slow <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		slow <- string(body)
+	}()
+
+	<-started
+	cancel()
+
+	// Во время задержки сервис еще принимает запросы, но /readyz отвечает 503
+	deadline := time.Now().Add(cfg.ShutdownDelay)
+	for {
+		resp, err := http.Get(baseURL + "/readyz")
+		if err != nil {
+			t.Fatalf("Сервис перестал принимать запросы до конца задержки: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Во время задержки ожидался статус 503, получен %d", resp.StatusCode)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if body := <-slow; body != "done" {
+		t.Errorf("Начатый запрос не завершился: %s", body)
+	}
+	if _, err := io.ReadAll(stream.Body); err != nil {
+		t.Errorf("Поток событий должен закрыться: %v", err)
+	}
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("Ошибка при остановке: %v", err)
+		}
+	case <-time.After(cfg.ShutdownTimeout):
+		t.Fatal("Сервис не остановился")
+	}
+
+	// После остановки сервис не готов принимать запросы
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Ожидался статус 503, получен %d", w.Code)
+	}
+}
+
+func TestRateLimitsAndQuotas(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.RateLimits = map[string]config.RateLimit{
+		"POST /schedule": {Rate: 0.01, Burst: 2},
+	}
+	cfg.MaxBodyBytes = 200
+	cfg.MaxSchedulesPerUser = 2
+	cfg.ValidateRequests = true
+	cfg.APIKeys = []string{"client-a", "client-b", "client-c"}
+	clk := clock.NewFake(time.Now())
+	server := NewServerWithClock(cfg, clk)
+
+	create := func(path, apiKey, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	// code возвращает код ошибки из ответа
+	code := func(w *httptest.ResponseRecorder) string {
+		var p problem.Problem
+		json.NewDecoder(w.Body).Decode(&p)
+		return p.Code
+	}
+	body := `{"user_id":"test123","medicine_name":"Аспирин","frequency":1,"duration":7}`
+
+	for i := 0; i < 2; i++ {
+		if w := create("/v1/schedule", "client-a", body); w.Code != http.StatusOK {
+			t.Fatalf("Ожидался статус 200, получен %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	// Токены клиента закончились, в том числе для устаревшего маршрута
+	for _, path := range []string{"/v1/schedule", "/schedule"} {
+		w := create(path, "client-a", body)
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("%s: ожидался статус 429, получен %d", path, w.Code)
+		}
+		if retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || retryAfter < 1 {
+			t.Errorf("Неверный Retry-After: %q", w.Header().Get("Retry-After"))
+		}
+		if c := code(w); c != problem.CodeRateLimited {
+			t.Errorf("Ожидался код %s, получен %s", problem.CodeRateLimited, c)
+		}
+	}
+
+	// У другого клиента свои токены, но у пользователя закончилась квота
+	w := create("/v1/schedule", "client-b", body)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Ожидался статус 409, получен %d", w.Code)
+	}
+	if c := code(w); c != storage.CodeScheduleQuota {
+		t.Errorf("Ожидался код %s, получен %s", storage.CodeScheduleQuota, c)
+	}
+
+	// Слишком большое тело запроса
+	large := `{"user_id":"other","medicine_name":"` + strings.Repeat("а", 200) + `","frequency":1,"duration":7}`
+	w = create("/v1/schedule", "client-c", large)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Ожидался статус 413, получен %d", w.Code)
+	}
+	if c := code(w); c != problem.CodeRequestTooLarge {
+		t.Errorf("Ожидался код %s, получен %s", problem.CodeRequestTooLarge, c)
+	}
+
+	// Неизвестные ключи не дают нового лимита: все запросы считаются по IP
+	for i := 0; i < 2; i++ {
+		body := fmt.Sprintf(`{"user_id":"user%d","medicine_name":"Аспирин","frequency":1,"duration":7}`, i)
+		if w := create("/v1/schedule?user_id="+strconv.Itoa(i), "random-"+strconv.Itoa(i), body); w.Code != http.StatusOK {
+			t.Fatalf("Ожидался статус 200, получен %d: %s", w.Code, w.Body.String())
+		}
+	}
+	if w := create("/v1/schedule?user_id=2", "random-2", body); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Ожидался статус 429 для нового ключа с того же IP, получен %d", w.Code)
+	}
+
+	// Токены пополняются по часам сервера
+	clk.Add(100 * time.Second)
+	other := `{"user_id":"user2","medicine_name":"Аспирин","frequency":1,"duration":7}`
+	if w := create("/v1/schedule", "random-2", other); w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200 после пополнения токенов, получен %d", w.Code)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	// Переменные окружения меняют значения по умолчанию, флаги - переменные окружения
+	t.Setenv("TAKE_A_PILL_ADMIN_API_KEY", "admin-secret")
+	t.Setenv("TAKE_A_PILL_API_KEYS", "client-a, client-b,")
+	t.Setenv("TAKE_A_PILL_LOG_HASH_KEY", "hash-key")
+	t.Setenv("TAKE_A_PILL_LOG_LEVEL", "warn")
+	cfg, err := config.Load([]string{"-log-level", "error", "-shutdown-timeout", "5s", "-reject-over-dose-limit=false", "-user-api-keys", "key-1=user1, key-2=user2"})
+	if err != nil {
+		t.Fatalf("Не удалось загрузить настройки: %v", err)
+	}
+	if cfg.AdminAPIKey != "admin-secret" || cfg.LogHashKey != "hash-key" || !reflect.DeepEqual(cfg.APIKeys, []string{"client-a", "client-b"}) {
+		t.Errorf("Настройки из окружения не применены: %+v", cfg)
+	}
+	if cfg.LogLevel != "error" || cfg.ShutdownTimeout != 5*time.Second || cfg.RejectOverDoseLimit || cfg.UserAPIKeys["key-2"] != "user2" {
+		t.Errorf("Флаги не применены: %+v", cfg)
+	}
+	if cfg.ServerPort != ":8081" {
+		t.Errorf("Ожидался адрес по умолчанию, получен %q", cfg.ServerPort)
+	}
+
+	// Некорректные значения не дают запустить сервис
+	for name, args := range map[string][]string{
+		"уровень логов":    {"-log-level", "verbose"},
+		"трассировка":      {"-trace-exporter", "jaeger"},
+		"TLS без ключа":    {"-tls-cert", "cert.pem"},
+		"интервал":         {"-due-doses-interval", "0s"},
+		"задержка":         {"-shutdown-delay", "-1s"},
+		"неизвестный флаг": {"-unknown"},
+		"ключ без user_id": {"-user-api-keys", "key-1"},
+		"лишние аргументы": {"serve"},
+	} {
+		if _, err := config.Load(args); err == nil {
+			t.Errorf("%s: ожидалась ошибка", name)
+		}
+	}
+	t.Setenv("TAKE_A_PILL_SHUTDOWN_TIMEOUT", "soon")
+	if _, err := config.Load(nil); err == nil || !strings.Contains(err.Error(), "TAKE_A_PILL_SHUTDOWN_TIMEOUT") {
+		t.Errorf("Ожидалась ошибка в переменной окружения, получено %v", err)
+	}
+}
+
+// newBenchmarkStorage создает хранилище с заданным числом пользователей,
+// у каждого из которых по три курса
+func newBenchmarkStorage(b *testing.B, users int) *storage.MemoryStorage {
+	b.Helper()
+	db := storage.NewMemoryStorage()
+	for i := 0; i < users; i++ {
+		for frequency := 1; frequency <= 3; frequency++ {
+			_, _, err := db.CreateSchedule(context.Background(), &models.ScheduleRequest{
+				UserID:       fmt.Sprintf("user-%d", i),
+				MedicineName: fmt.Sprintf("Лекарство %d", frequency),
+				Frequency:    frequency,
+				Duration:     30,
+			})
+			if err != nil {
+				b.Fatalf("Не удалось создать расписание: %v", err)
+			}
+		}
+	}
+	return db
+}
+
+func BenchmarkGetNextTakings(b *testing.B) {
+	for _, users := range []int{1000, 10000} {
+		b.Run(fmt.Sprintf("users=%d", users), func(b *testing.B) {
+			db := newBenchmarkStorage(b, users)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				db.GetNextTakings(context.Background(), fmt.Sprintf("user-%d", i%users))
+			}
+		})
+	}
+}
+
+func BenchmarkGetSchedulesByUserID(b *testing.B) {
+	for _, users := range []int{1000, 10000} {
+		b.Run(fmt.Sprintf("users=%d", users), func(b *testing.B) {
+			db := newBenchmarkStorage(b, users)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				db.GetSchedulesByUserID(context.Background(), fmt.Sprintf("user-%d", i%users))
+			}
+		})
+	}
+}
+
+// BenchmarkGetNextTakingsWithWrites измеряет чтение ближайших приемов,
+// пока другие пользователи приостанавливают и возобновляют курсы
+func BenchmarkGetNextTakingsWithWrites(b *testing.B) {
+	const users = 10000
+	db := newBenchmarkStorage(b, users)
+	scheduleIDs := db.GetSchedulesByUserID(context.Background(), "user-0")
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for paused := true; ; paused = !paused {
+			select {
+			case <-stop:
+				return
+			default:
+				db.SetPaused(context.Background(), scheduleIDs[0], 0, paused)
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			db.GetNextTakings(context.Background(), fmt.Sprintf("user-%d", 1+i%(users-1)))
+			i++
+		}
+	})
 }
@@ -0,0 +1,115 @@
+// Package metrics собирает метрики сервиса в формате Prometheus
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"take-a-pill/storage"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Префикс имен всех метрик сервиса
+const namespace = "takeapill"
+
+// Metrics хранит метрики сервиса и отдает их Prometheus
+type Metrics struct {
+	registry *prometheus.Registry
+	// Запросы к API по маршрутам
+	requests *prometheus.CounterVec
+	// Время обработки запросов по маршрутам
+	requestDuration *prometheus.HistogramVec
+	// Время вычисления ближайших приемов
+	nextTakingsDuration prometheus.Histogram
+}
+
+// New создает метрики сервиса. Число расписаний и пользователей
+// берется из хранилища в момент запроса метрик.
+func New(db *storage.MemoryStorage) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Число запросов к API по маршрутам и статусам ответа.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Время обработки запросов к API.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		nextTakingsDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "next_takings_duration_seconds",
+			Help:      "Время вычисления ближайших приемов пользователя.",
+			Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.nextTakingsDuration,
+		newStorageCollector(db),
+	)
+	return m
+}
+
+// Handler возвращает обработчик, отдающий метрики в формате Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest учитывает обработанный запрос к API
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	m.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.requestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// ObserveNextTakings учитывает время вычисления ближайших приемов
+func (m *Metrics) ObserveNextTakings(duration time.Duration) {
+	m.nextTakingsDuration.Observe(duration.Seconds())
+}
+
+// storageCollector отдает число расписаний и пользователей в хранилище.
+// Сводка считается один раз на каждый запрос метрик.
+type storageCollector struct {
+	db          *storage.MemoryStorage
+	schedules   *prometheus.Desc
+	users       *prometheus.Desc
+	activeUsers *prometheus.Desc
+}
+
+func newStorageCollector(db *storage.MemoryStorage) *storageCollector {
+	return &storageCollector{
+		db: db,
+		schedules: prometheus.NewDesc(namespace+"_schedules",
+			"Число расписаний по состоянию: active - идет прием, inactive - приостановлено или завершено.",
+			[]string{"state"}, nil),
+		users: prometheus.NewDesc(namespace+"_users",
+			"Число пользователей, у которых есть расписания.", nil, nil),
+		activeUsers: prometheus.NewDesc(namespace+"_active_users",
+			"Число пользователей, у которых есть активные расписания.", nil, nil),
+	}
+}
+
+func (c *storageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.schedules
+	ch <- c.users
+	ch <- c.activeUsers
+}
+
+func (c *storageCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.Stats()
+	ch <- prometheus.MustNewConstMetric(c.schedules, prometheus.GaugeValue, float64(stats.ActiveSchedules), "active")
+	ch <- prometheus.MustNewConstMetric(c.schedules, prometheus.GaugeValue, float64(stats.Schedules-stats.ActiveSchedules), "inactive")
+	ch <- prometheus.MustNewConstMetric(c.users, prometheus.GaugeValue, float64(stats.Users))
+	ch <- prometheus.MustNewConstMetric(c.activeUsers, prometheus.GaugeValue, float64(stats.ActiveUsers))
+}
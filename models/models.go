@@ -1,6 +1,7 @@
 package models
 
 import (
+	"slices"
 	"time"
 )
 
@@ -14,6 +15,57 @@ type ScheduleRequest struct {
 	Frequency int `json:"frequency"`
 	// Сколько дней принимать (0 - постоянный прием, >0 - количество дней)
 	Duration int `json:"duration"`
+	// Как распределить приемы по дню: even, front_loaded или meal_anchored
+	// (необязательно, по умолчанию из настроек)
+	Distribution string `json:"distribution,omitempty"`
+	// Приемы относительно еды (необязательно). Если указаны, времена приема
+	// считаются по времени еды пользователя, а частоту можно не указывать.
+	MealDoses []MealDose `json:"meal_doses,omitempty"`
+	// Начальный запас таблеток (необязательно)
+	Inventory *Inventory `json:"inventory,omitempty"`
+	// ID рецепта, по которому назначен курс (необязательно)
+	PrescriptionID string `json:"prescription_id,omitempty"`
+	// ID лекарства из справочника (необязательно)
+	MedicineID string `json:"medicine_id,omitempty"`
+}
+
+// Структура для ответа на создание расписания
+type CreateScheduleResponse struct {
+	ScheduleID string `json:"schedule_id"`
+	// Предупреждения, которые не помешали создать расписание
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// Структура для предварительного просмотра расписания
+type SchedulePreview struct {
+	// Название лекарства
+	MedicineName string `json:"medicine_name"`
+	// Сколько раз в день принимать
+	Frequency int `json:"frequency"`
+	// Сколько дней принимать
+	Duration int `json:"duration"`
+	// Рассчитанные времена приема
+	TakingTimes []TakingTime `json:"taking_times"`
+	// Когда будет первый прием
+	FirstDose time.Time `json:"first_dose"`
+	// Когда будет последний прием (нет для постоянного приема)
+	LastDose *time.Time `json:"last_dose,omitempty"`
+	// Сколько всего будет приемов (0 - постоянный прием)
+	TotalDoses int `json:"total_doses"`
+	// Предупреждения, которые появятся при создании расписания
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// Структура для предупреждения пользователю
+type Warning struct {
+	// Машиночитаемый код предупреждения
+	Code string `json:"code"`
+	// Текст предупреждения
+	Message string `json:"message"`
+	// Тяжесть (для предупреждений о взаимодействии лекарств)
+	Severity string `json:"severity,omitempty"`
+	// Аргументы для текста предупреждения на другом языке
+	Args []any `json:"-"`
 }
 
 // Структура для хранения расписания
@@ -32,6 +84,101 @@ type Schedule struct {
 	CreatedAt time.Time `json:"created_at"`
 	// Рассчитанные времена приема
 	TakingTimes []TakingTime `json:"taking_times"`
+	// Как распределены приемы по дню (нет для приемов относительно еды)
+	Distribution string `json:"distribution,omitempty"`
+	// Приемы относительно еды
+	MealDoses []MealDose `json:"meal_doses,omitempty"`
+	// Запас таблеток
+	Inventory *Inventory `json:"inventory,omitempty"`
+	// ID рецепта, по которому назначен курс
+	PrescriptionID string `json:"prescription_id,omitempty"`
+	// ID лекарства из справочника
+	MedicineID string `json:"medicine_id,omitempty"`
+	// Приостановлен ли курс
+	Paused bool `json:"paused"`
+	// Версия расписания, растет при каждом изменении
+	Version int `json:"version"`
+}
+
+// Состояния расписания
+const (
+	// Курс идет
+	StatusActive = "active"
+	// Курс закончился
+	StatusExpired = "expired"
+	// Курс приостановлен пользователем
+	StatusPaused = "paused"
+)
+
+// Status возвращает состояние расписания в заданный момент
+func (s *Schedule) Status(now time.Time) string {
+	if !s.IsActive(now) {
+		return StatusExpired
+	}
+	if s.Paused {
+		return StatusPaused
+	}
+	return StatusActive
+}
+
+// Clone возвращает копию расписания, которая не меняется вместе с исходным
+func (s *Schedule) Clone() *Schedule {
+	copied := *s
+	copied.TakingTimes = slices.Clone(s.TakingTimes)
+	copied.MealDoses = slices.Clone(s.MealDoses)
+	if s.Inventory != nil {
+		inventory := *s.Inventory
+		copied.Inventory = &inventory
+	}
+	return &copied
+}
+
+// IsActive проверяет, идет ли еще курс в заданный момент
+func (s *Schedule) IsActive(now time.Time) bool {
+	// Постоянный прием не заканчивается
+	if s.Duration == 0 {
+		return true
+	}
+	return now.Before(s.CreatedAt.AddDate(0, 0, s.Duration))
+}
+
+// Приемы пищи
+const (
+	MealBreakfast = "breakfast"
+	MealLunch     = "lunch"
+	MealDinner    = "dinner"
+)
+
+// Meals - приемы пищи в порядке в течение дня
+var Meals = []string{MealBreakfast, MealLunch, MealDinner}
+
+// Когда принимать лекарство относительно еды
+const (
+	MealRelationBefore = "before"
+	MealRelationWith   = "with"
+	MealRelationAfter  = "after"
+)
+
+// Структура для приема лекарства относительно еды
+type MealDose struct {
+	// Прием пищи: breakfast, lunch или dinner
+	Meal string `json:"meal"`
+	// До еды (before), во время (with) или после (after)
+	Relation string `json:"relation"`
+	// За сколько минут до еды или через сколько минут после
+	OffsetMinutes int `json:"offset_minutes,omitempty"`
+}
+
+// Структура для хранения запаса таблеток по расписанию
+type Inventory struct {
+	// Сколько таблеток осталось
+	PillsOnHand int `json:"pills_on_hand"`
+	// Сколько таблеток в одной упаковке
+	PackSize int `json:"pack_size"`
+	// Сколько таблеток принимается за один раз
+	PillsPerDose int `json:"pills_per_dose"`
+	// Отправлено ли уже предупреждение о заканчивающемся запасе
+	LowStockNotified bool `json:"-"`
 }
 
 // Структура для хранения времени приема
@@ -64,50 +211,6 @@ func (t *TakingTime) IsWithinDayHours() bool {
 	return t.Hour >= 8 && t.Hour < 22
 }
 
-// CalculateTakingTimes рассчитывает времена приема для заданной частоты
-func CalculateTakingTimes(frequency int) []TakingTime {
-	dayStart := 8 // 8:00
-	dayEnd := 22  // 22:00
-	totalMinutes := (dayEnd - dayStart) * 60
-
-	// Если прием 1 раз в день - фиксированное время 9:00
-	if frequency == 1 {
-		return []TakingTime{{Hour: 9, Minute: 0}}
-	}
-
-	// Рассчитываем интервал между приемами в минутах
-	interval := totalMinutes / (frequency + 1) // Добавляем 1 для равномерного распределения
-
-	var times []TakingTime
-	for i := 1; i <= frequency; i++ {
-		// Рассчитываем время в минутах от начала дня
-		totalMinutes := (dayStart * 60) + (i * interval)
-
-		// Переводим в часы и минуты
-		hour := totalMinutes / 60
-		minute := totalMinutes % 60
-
-		// Округляем минуты до ближайших 15
-		minute = ((minute + 7) / 15) * 15
-
-		// Корректируем час если минуты стали 60
-		if minute == 60 {
-			minute = 0
-			hour++
-		}
-
-		// Проверяем что время попадает в диапазон 8:00-22:00
-		if hour >= dayStart && hour < dayEnd {
-			times = append(times, TakingTime{
-				Hour:   hour,
-				Minute: minute,
-			})
-		}
-	}
-
-	return times
-}
-
 // Структура для ответа со списком расписаний
 type SchedulesResponse struct {
 	ScheduleIDs []string `json:"schedule_ids"`
@@ -130,3 +233,154 @@ type NextTaking struct {
 	MedicineName   string     `json:"medicine_name"`
 	NextTakingTime TakingTime `json:"next_taking_time"`
 }
+
+// Структура для запроса на изменение запаса таблеток
+type InventoryRequest struct {
+	UserID     string `json:"user_id"`
+	ScheduleID string `json:"schedule_id"`
+	Inventory
+}
+
+// Структура для запроса на отметку о приеме лекарства
+type DoseRequest struct {
+	UserID     string `json:"user_id"`
+	ScheduleID string `json:"schedule_id"`
+}
+
+// Структура для запроса на пополнение запаса
+type RefillRequest struct {
+	UserID     string `json:"user_id"`
+	ScheduleID string `json:"schedule_id"`
+	// Сколько упаковок добавлено (по умолчанию 1)
+	Packs int `json:"packs"`
+}
+
+// Структура для ответа с прогнозом окончания запаса
+type InventoryForecast struct {
+	ScheduleID string `json:"schedule_id"`
+	// Версия расписания после операции
+	ScheduleVersion int       `json:"schedule_version"`
+	Inventory       Inventory `json:"inventory"`
+	// Сколько таблеток расходуется в день
+	DailyConsumption int `json:"daily_consumption"`
+	// На сколько полных дней хватит запаса
+	DaysLeft int `json:"days_left"`
+	// Дата, когда закончатся таблетки
+	RunOutDate string `json:"run_out_date,omitempty"`
+	// Хватит ли запаса до конца курса
+	EnoughForCourse bool `json:"enough_for_course"`
+	// Пора ли пополнить запас
+	LowStock bool `json:"low_stock"`
+	// Предупреждения, которые не помешали изменить запас
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// Структура для запроса на создание рецепта
+type PrescriptionRequest struct {
+	// ID пользователя
+	UserID string `json:"user_id"`
+	// Название лекарства
+	MedicineName string `json:"medicine_name"`
+	// Врач, выписавший рецепт
+	Prescriber string `json:"prescriber"`
+	// Дата выписки рецепта
+	IssueDate time.Time `json:"issue_date"`
+	// До какого момента рецепт действителен
+	ValidUntil time.Time `json:"valid_until"`
+	// Сколько раз можно получить лекарство повторно
+	Refills int `json:"refills"`
+}
+
+// Структура для хранения рецепта
+type Prescription struct {
+	// Уникальный ID рецепта
+	ID string `json:"id"`
+	// ID пользователя
+	UserID string `json:"user_id"`
+	// Название лекарства
+	MedicineName string `json:"medicine_name"`
+	// Врач, выписавший рецепт
+	Prescriber string `json:"prescriber"`
+	// Дата выписки рецепта
+	IssueDate time.Time `json:"issue_date"`
+	// До какого момента рецепт действителен
+	ValidUntil time.Time `json:"valid_until"`
+	// Сколько повторных выдач осталось
+	RefillsRemaining int `json:"refills_remaining"`
+	// ID расписаний, созданных по рецепту
+	ScheduleIDs []string `json:"schedule_ids"`
+	// Время создания рецепта
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Clone возвращает копию рецепта, которая не меняется вместе с исходным
+func (p *Prescription) Clone() *Prescription {
+	copied := *p
+	copied.ScheduleIDs = slices.Clone(p.ScheduleIDs)
+	return &copied
+}
+
+// Структура для запроса на использование повторной выдачи по рецепту
+type PrescriptionRefillRequest struct {
+	UserID         string `json:"user_id"`
+	PrescriptionID string `json:"prescription_id"`
+}
+
+// Структура для хранения настроек пользователя
+type UserProfile struct {
+	// ID пользователя
+	UserID string `json:"user_id"`
+	// Язык сообщений и уведомлений (ru, en)
+	Language string `json:"language,omitempty"`
+	// Время приемов пищи: breakfast, lunch, dinner. Для неуказанных
+	// берется время из настроек сервиса.
+	Meals map[string]TakingTime `json:"meals,omitempty"`
+	// Версия настроек, растет при каждом сохранении. В запросе не учитывается.
+	Version int `json:"version,omitempty"`
+}
+
+// Структура для ответа со списком расписаний
+type ScheduleListResponse struct {
+	Schedules []*Schedule `json:"schedules"`
+	// Курсор для получения следующей страницы (пустой, если страниц больше нет)
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Структура для запроса на объединение напоминаний пользователя
+type ConsolidationRequest struct {
+	UserID string `json:"user_id"`
+}
+
+// Структура для напоминания - момента, когда нужно принять одно или несколько лекарств
+type Reminder struct {
+	Time TakingTime `json:"time"`
+	// Названия лекарств, которые принимаются в этот момент
+	MedicineNames []string `json:"medicine_names"`
+}
+
+// Структура для изменения времен приема курса
+type TakingTimesChange struct {
+	ScheduleID   string       `json:"schedule_id"`
+	MedicineName string       `json:"medicine_name"`
+	Before       []TakingTime `json:"before"`
+	After        []TakingTime `json:"after"`
+}
+
+// Структура для результата объединения напоминаний
+type Consolidation struct {
+	// Напоминания за день до и после объединения
+	Before []Reminder `json:"before"`
+	After  []Reminder `json:"after"`
+	// Курсы, времена приема которых сдвигаются
+	Changes []TakingTimesChange `json:"changes"`
+	// Сохранены ли новые времена приема
+	Applied bool `json:"applied"`
+	// Метка состояния курсов пользователя, отдается в заголовке ETag
+	Tag string `json:"-"`
+}
+
+// Структура для запроса на приостановку или возобновление курса
+type PauseRequest struct {
+	UserID     string `json:"user_id"`
+	ScheduleID string `json:"schedule_id"`
+}
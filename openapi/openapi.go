@@ -0,0 +1,194 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// Префикс актуальной версии API. Устаревшие маршруты без версии
+// проверяются по описанию соответствующих маршрутов /v1.
+const versionPrefix = "/v1"
+
+// Operation описывает операцию из спецификации
+type Operation struct {
+	Method string
+	Path   string
+}
+
+func (o Operation) String() string {
+	return o.Method + " " + o.Path
+}
+
+// Validator проверяет запросы и ответы по спецификации OpenAPI
+type Validator struct {
+	doc    *openapi3.T
+	router routers.Router
+}
+
+// Load загружает и проверяет спецификацию из YAML или JSON файла
+func Load(path string) (*Validator, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось загрузить спецификацию %s: %w", path, err)
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("некорректная спецификация %s: %w", path, err)
+	}
+
+	// Сервер из спецификации не учитываем, чтобы проверка не зависела
+	// от адреса, на котором запущен сервис
+	routed := *doc
+	routed.Servers = nil
+	router, err := gorillamux.NewRouter(&routed)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось построить маршруты спецификации %s: %w", path, err)
+	}
+
+	return &Validator{doc: doc, router: router}, nil
+}
+
+// Operations возвращает все операции спецификации в стабильном порядке
+func (v *Validator) Operations() []Operation {
+	var operations []Operation
+	for _, path := range v.doc.Paths.InMatchingOrder() {
+		for method := range v.doc.Paths.Value(path).Operations() {
+			operations = append(operations, Operation{Method: method, Path: path})
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		if operations[i].Path != operations[j].Path {
+			return operations[i].Path < operations[j].Path
+		}
+		return operations[i].Method < operations[j].Method
+	})
+	return operations
+}
+
+// Find возвращает операцию спецификации, которой соответствует запрос.
+// ok равен false, если запрос не описан в спецификации.
+func (v *Validator) Find(r *http.Request) (Operation, bool) {
+	route, _, ok := v.route(r)
+	if !ok {
+		return Operation{}, false
+	}
+	return Operation{Method: route.Method, Path: route.Path}, true
+}
+
+// Streaming проверяет, отвечает ли операция потоком событий. Такие ответы
+// не буферизуются и не проверяются.
+func (v *Validator) Streaming(r *http.Request) bool {
+	route, _, ok := v.route(r)
+	if !ok || route.Operation == nil {
+		return false
+	}
+	response := route.Operation.Responses.Status(http.StatusOK)
+	if response == nil || response.Value == nil {
+		return false
+	}
+	return response.Value.Content.Get("text/event-stream") != nil
+}
+
+// ValidateRequest проверяет запрос по спецификации. Запросы, которые
+// не описаны в спецификации, не проверяются. Тело запроса остается доступным.
+func (v *Validator) ValidateRequest(r *http.Request) error {
+	input, ok, err := v.requestInput(r)
+	if !ok || err != nil {
+		return err
+	}
+	return openapi3filter.ValidateRequest(context.Background(), input)
+}
+
+// ValidateResponse проверяет ответ на запрос по спецификации
+func (v *Validator) ValidateResponse(r *http.Request, status int, header http.Header, body []byte) error {
+	input, ok, err := v.requestInput(r)
+	if !ok || err != nil {
+		return err
+	}
+	return openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 status,
+		Header:                 header,
+		Body:                   io.NopCloser(bytes.NewReader(body)),
+		Options:                input.Options,
+	})
+}
+
+// Reason возвращает описание ошибки проверки без значения, которое ее
+// вызвало: путь к полю и причину. Значение может содержать медицинские
+// данные, поэтому полный текст ошибки можно писать только в отладочный лог.
+func Reason(err error) string {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		return "/" + strings.Join(schemaErr.JSONPointer(), "/") + ": " + schemaErr.Reason
+	}
+	var responseErr *openapi3filter.ResponseError
+	if errors.As(err, &responseErr) && responseErr.Reason != "" {
+		return responseErr.Reason
+	}
+	var requestErr *openapi3filter.RequestError
+	if errors.As(err, &requestErr) && requestErr.Reason != "" {
+		return requestErr.Reason
+	}
+	return "неизвестная ошибка проверки"
+}
+
+// requestInput готовит данные для проверки запроса. Тело запроса читается
+// и подменяется копией, чтобы его можно было прочитать еще раз.
+func (v *Validator) requestInput(r *http.Request) (*openapi3filter.RequestValidationInput, bool, error) {
+	route, params, ok := v.route(r)
+	if !ok {
+		return nil, false, nil
+	}
+
+	req := r
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, true, err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		req = r.Clone(r.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	return &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: params,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+			IncludeResponseStatus: true,
+		},
+	}, true, nil
+}
+
+// route находит маршрут спецификации для запроса
+func (v *Validator) route(r *http.Request) (*routers.Route, map[string]string, bool) {
+	if route, params, err := v.router.FindRoute(r); err == nil {
+		return route, params, true
+	}
+
+	// Устаревший маршрут без версии ищем среди маршрутов /v1
+	if strings.HasPrefix(r.URL.Path, versionPrefix+"/") {
+		return nil, nil, false
+	}
+	versioned := r.Clone(r.Context())
+	versioned.URL.Path = versionPrefix + r.URL.Path
+	versioned.URL.RawPath = ""
+	if route, params, err := v.router.FindRoute(versioned); err == nil {
+		return route, params, true
+	}
+	return nil, nil, false
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+
+	"take-a-pill/logging"
+	"take-a-pill/openapi"
+	"take-a-pill/problem"
+)
+
+// bufferedResponse накапливает ответ, чтобы проверить его до отправки клиенту
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) Write(data []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(data)
+}
+
+// validateSpec проверяет запросы по спецификации API, а если включено
+// в настройках, то и ответы. Запросы, не описанные в спецификации, пропускаются.
+func (s *Server) validateSpec(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.cfg.ValidateRequests {
+			if err := s.spec.ValidateRequest(r); err != nil {
+				if maxBytesErr, ok := tooLarge(err); ok {
+					s.writeProblem(w, r, http.StatusRequestEntityTooLarge, problem.CodeRequestTooLarge, maxBytesErr.Limit)
+					return
+				}
+				s.writeProblem(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err)
+				return
+			}
+		}
+
+		if !s.cfg.ValidateResponses || s.spec.Streaming(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		response := &bufferedResponse{header: make(http.Header)}
+		next.ServeHTTP(response, r)
+		if response.status == 0 {
+			response.status = http.StatusOK
+		}
+
+		if err := s.spec.ValidateResponse(r, response.status, response.header, response.body.Bytes()); err != nil {
+			// Ошибка содержит значение из ответа, например название лекарства,
+			// поэтому полностью пишется только в отладочный лог
+			logger := logging.FromContext(r.Context())
+			logger.Error("ответ не соответствует спецификации", "reason", openapi.Reason(err))
+			logger.Debug("ответ не соответствует спецификации", "error", err)
+			s.writeProblem(w, r, http.StatusInternalServerError, problem.CodeInvalidResponse, openapi.Reason(err))
+			return
+		}
+
+		for name, values := range response.header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(response.status)
+		w.Write(response.body.Bytes())
+	})
+}
@@ -0,0 +1,5 @@
+// Package pillpb содержит описание gRPC API и сгенерированный по нему код.
+// Для генерации нужны buf, protoc-gen-go и protoc-gen-go-grpc.
+package pillpb
+
+//go:generate buf generate
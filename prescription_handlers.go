@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"take-a-pill/models"
+	"take-a-pill/storage"
+)
+
+// Обработчик для создания рецепта
+func (s *Server) createPrescription(w http.ResponseWriter, r *http.Request) {
+	var request models.PrescriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		s.writeInvalidJSON(w, r, err)
+		return
+	}
+
+	prescription, err := s.db.CreatePrescription(r.Context(), &request)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"prescription_id": prescription.ID,
+	})
+}
+
+// Обработчик для получения деталей рецепта
+func (s *Server) getPrescription(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	prescriptionID := r.URL.Query().Get("prescription_id")
+
+	prescription, ok := s.userPrescription(w, r, userID, prescriptionID)
+	if !ok {
+		return
+	}
+
+	s.writePrescription(w, prescription)
+}
+
+// Обработчик для получения списка рецептов пользователя
+func (s *Server) getPrescriptions(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		s.writeMissingParameter(w, r, "user_id")
+		return
+	}
+
+	prescriptionIDs := s.db.GetPrescriptionsByUserID(r.Context(), userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{
+		"prescription_ids": prescriptionIDs,
+	})
+}
+
+// Обработчик для списания повторной выдачи по рецепту
+func (s *Server) usePrescriptionRefill(w http.ResponseWriter, r *http.Request) {
+	var request models.PrescriptionRefillRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		s.writeInvalidJSON(w, r, err)
+		return
+	}
+
+	if _, ok := s.userPrescription(w, r, request.UserID, request.PrescriptionID); !ok {
+		return
+	}
+
+	prescription, err := s.db.UsePrescriptionRefill(r.Context(), request.PrescriptionID)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writePrescription(w, prescription)
+}
+
+// userPrescription находит рецепт и проверяет, что он принадлежит пользователю.
+// При ошибке сам отправляет ответ клиенту и возвращает false.
+func (s *Server) userPrescription(w http.ResponseWriter, r *http.Request, userID, prescriptionID string) (*models.Prescription, bool) {
+	if userID == "" {
+		s.writeMissingParameter(w, r, "user_id")
+		return nil, false
+	}
+
+	if prescriptionID == "" {
+		s.writeMissingParameter(w, r, "prescription_id")
+		return nil, false
+	}
+
+	prescription, err := s.db.GetPrescriptionByID(r.Context(), prescriptionID)
+	if err != nil || prescription.UserID != userID {
+		s.writeProblem(w, r, http.StatusNotFound, storage.CodePrescriptionNotFound)
+		return nil, false
+	}
+
+	return prescription, true
+}
+
+// writePrescription отправляет рецепт в ответе
+func (s *Server) writePrescription(w http.ResponseWriter, prescription *models.Prescription) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(prescription); err != nil {
+		s.logger.Warn("ошибка при отправке ответа", "error", err)
+	}
+}
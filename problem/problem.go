@@ -0,0 +1,77 @@
+package problem
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"take-a-pill/validation"
+)
+
+// ContentType - тип содержимого ответа с ошибкой по RFC 7807
+const ContentType = "application/problem+json"
+
+// Общие коды ошибок API
+const (
+	CodeInvalidJSON          = "invalid_json"
+	CodeMissingParameter     = "missing_parameter"
+	CodeInvalidParameter     = "invalid_parameter"
+	CodeValidationFailed     = "validation_failed"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodePreconditionRequired = "precondition_required"
+	CodeInternal             = "internal_error"
+	CodeInvalidRequest       = "invalid_request"
+	CodeInvalidResponse      = "invalid_response"
+	CodeShuttingDown         = "shutting_down"
+	CodeRateLimited          = "rate_limited"
+	CodeRequestTooLarge      = "request_too_large"
+	CodeAdminOnly            = "admin_only"
+	CodeAdminRequired        = "admin_required"
+	CodeAPIKeyRequired       = "api_key_required"
+	CodeForeignUser          = "foreign_user"
+)
+
+// Problem описывает ошибку в формате RFC 7807
+type Problem struct {
+	// URI, определяющий тип ошибки
+	Type string `json:"type"`
+	// Краткое описание типа ошибки
+	Title string `json:"title"`
+	// HTTP статус ответа
+	Status int `json:"status"`
+	// Подробное описание ошибки
+	Detail string `json:"detail,omitempty"`
+	// Путь запроса, в котором произошла ошибка
+	Instance string `json:"instance,omitempty"`
+	// Машиночитаемый код ошибки
+	Code string `json:"code"`
+	// Ошибки в отдельных полях запроса
+	Errors []validation.FieldError `json:"errors,omitempty"`
+	// Дополнительные данные об ошибке
+	Details any `json:"details,omitempty"`
+}
+
+// New создает описание ошибки с заданным статусом и кодом
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   "urn:take-a-pill:problem:" + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Write отправляет описание ошибки в ответе
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		slog.Warn("ошибка при отправке ответа", "error", err)
+	}
+}
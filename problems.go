@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"take-a-pill/i18n"
+	"take-a-pill/interactions"
+	"take-a-pill/logging"
+	"take-a-pill/problem"
+	"take-a-pill/storage"
+	"take-a-pill/validation"
+)
+
+// localizer реализуется ошибками, текст которых можно получить на другом языке
+type localizer interface {
+	Localize(lang i18n.Lang) string
+}
+
+// language выбирает язык ответа: из заголовка Accept-Language,
+// затем из настроек пользователя, иначе язык по умолчанию
+func (s *Server) language(r *http.Request, userID string) i18n.Lang {
+	if lang, ok := i18n.ParseAcceptLanguage(r.Header.Get("Accept-Language")); ok {
+		return lang
+	}
+
+	if userID != "" {
+		if profile, err := s.db.GetProfile(r.Context(), userID); err == nil && profile.Language != "" {
+			return i18n.Lang(profile.Language)
+		}
+	}
+
+	return i18n.Default
+}
+
+// requestLanguage выбирает язык ответа для запроса с user_id в параметрах
+func (s *Server) requestLanguage(r *http.Request) i18n.Lang {
+	return s.language(r, r.URL.Query().Get("user_id"))
+}
+
+// writeProblem отправляет ошибку в формате application/problem+json.
+// Код ошибки является и ключом ее текста в каталоге сообщений.
+func (s *Server) writeProblem(w http.ResponseWriter, r *http.Request, status int, code string, args ...any) {
+	problem.Write(w, r, problem.New(status, code, i18n.T(s.requestLanguage(r), code, args...)))
+}
+
+// writeMissingParameter сообщает, что в запросе не указан обязательный параметр
+func (s *Server) writeMissingParameter(w http.ResponseWriter, r *http.Request, name string) {
+	s.writeProblem(w, r, http.StatusBadRequest, problem.CodeMissingParameter, name)
+}
+
+// writeInvalidJSON сообщает, что тело запроса не удалось прочитать
+func (s *Server) writeInvalidJSON(w http.ResponseWriter, r *http.Request, err error) {
+	if maxBytesErr, ok := tooLarge(err); ok {
+		s.writeProblem(w, r, http.StatusRequestEntityTooLarge, problem.CodeRequestTooLarge, maxBytesErr.Limit)
+		return
+	}
+	s.writeProblem(w, r, http.StatusBadRequest, problem.CodeInvalidJSON)
+}
+
+// writeError отправляет ошибку, выбирая HTTP статус и код по ее типу
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	lang := s.requestLanguage(r)
+
+	var fieldErrors validation.Errors
+	if errors.As(err, &fieldErrors) {
+		p := problem.New(http.StatusBadRequest, problem.CodeValidationFailed, i18n.T(lang, problem.CodeValidationFailed))
+		p.Errors = fieldErrors.Localize(lang)
+		problem.Write(w, r, p)
+		return
+	}
+
+	var contraindication *interactions.ContraindicationError
+	if errors.As(err, &contraindication) {
+		p := problem.New(http.StatusConflict, storage.CodeContraindicated, contraindication.Localize(lang))
+		p.Details = contraindication.Findings
+		problem.Write(w, r, p)
+		return
+	}
+
+	var doseLimit *storage.DoseLimitError
+	if errors.As(err, &doseLimit) {
+		p := problem.New(http.StatusUnprocessableEntity, storage.CodeDailyDoseLimit, doseLimit.Localize(lang))
+		p.Details = doseLimit.Excesses
+		problem.Write(w, r, p)
+		return
+	}
+
+	var storageErr *storage.Error
+	if errors.As(err, &storageErr) {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, storage.ErrConflict):
+			status = http.StatusConflict
+		case errors.Is(err, storage.ErrInvalid):
+			status = http.StatusBadRequest
+		case errors.Is(err, storage.ErrUnavailable):
+			status = http.StatusServiceUnavailable
+		case errors.Is(err, storage.ErrPreconditionFailed):
+			status = http.StatusPreconditionFailed
+		}
+		problem.Write(w, r, problem.New(status, storageErr.Code, storageErr.Localize(lang)))
+		return
+	}
+
+	logging.FromContext(r.Context()).Error("внутренняя ошибка", "error", err)
+	s.writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal)
+}
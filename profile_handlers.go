@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"take-a-pill/events"
+	"take-a-pill/i18n"
+	"take-a-pill/models"
+)
+
+// Обработчик для получения настроек пользователя
+func (s *Server) getProfile(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		s.writeMissingParameter(w, r, "user_id")
+		return
+	}
+
+	profile, err := s.db.GetProfile(r.Context(), userID)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeProfile(w, profile)
+}
+
+// Обработчик для сохранения настроек пользователя. Требует If-Match
+// с ETag настроек или "*", чтобы не перезаписать изменения с другого устройства.
+func (s *Server) setProfile(w http.ResponseWriter, r *http.Request) {
+	var request models.UserProfile
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		s.writeInvalidJSON(w, r, err)
+		return
+	}
+
+	profile, err := s.db.SetProfile(r.Context(), &request, ifMatchVersion(r))
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeProfile(w, profile)
+}
+
+// writeProfile отправляет настройки пользователя с их ETag
+func (s *Server) writeProfile(w http.ResponseWriter, profile *models.UserProfile) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(profile.Version))
+	json.NewEncoder(w).Encode(profile)
+}
+
+// notificationText возвращает текст уведомления о событии на языке пользователя.
+// Для событий без уведомления возвращает пустую строку.
+func (s *Server) notificationText(e events.Event) string {
+	lang := i18n.Default
+	if profile, err := s.db.GetProfile(context.Background(), e.UserID); err == nil && profile.Language != "" {
+		lang = i18n.Lang(profile.Language)
+	}
+
+	switch e.Type {
+	case events.TypeLowStock:
+		return i18n.T(lang, "notification."+e.Type,
+			e.Data["medicine_name"], e.Data["pills_on_hand"], e.Data["days_left"], e.Data["run_out_date"])
+	case events.TypeDoseTaken:
+		return i18n.T(lang, "notification."+e.Type, e.Data["medicine_name"])
+	case events.TypeDoseDue:
+		return i18n.T(lang, "notification."+e.Type, e.Data["medicine_name"], e.Data["hour"], e.Data["minute"])
+	}
+	return ""
+}
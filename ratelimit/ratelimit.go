@@ -0,0 +1,95 @@
+// Package ratelimit ограничивает частоту запросов клиентов алгоритмом token bucket
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"take-a-pill/clock"
+)
+
+// Как часто удалять корзины клиентов, которые давно не присылали запросов
+const sweepInterval = time.Minute
+
+// Limit задает ограничение частоты запросов
+type Limit struct {
+	// Сколько запросов в секунду можно делать в среднем. 0 - без ограничений.
+	Rate float64
+	// Сколько запросов можно сделать подряд, если клиент долго не присылал запросов
+	Burst int
+}
+
+// bucket хранит токены одного клиента
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// Когда корзина снова наполнится и ее можно будет удалить
+	full time.Time
+}
+
+// Limiter хранит корзины токенов клиентов в памяти
+type Limiter struct {
+	// Часы, по которым наполняются корзины
+	clock     clock.Clock
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// New создает ограничитель частоты запросов, который берет время из заданных часов
+func New(c clock.Clock) *Limiter {
+	return &Limiter{
+		clock:   c,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow забирает токен из корзины клиента с ключом key. Если токенов нет,
+// возвращает false и время, через которое появится следующий токен.
+func (l *Limiter) Allow(key string, limit Limit) (bool, time.Duration) {
+	if limit.Rate <= 0 {
+		return true, 0
+	}
+	burst := math.Max(float64(limit.Burst), 1)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, updated: now}
+		l.buckets[key] = b
+	}
+
+	// Пополняем корзину за время с прошлого запроса
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	b.full = now.Add(time.Duration((burst - b.tokens) / limit.Rate * float64(time.Second)))
+	return true, 0
+}
+
+// sweep удаляет корзины, которые уже наполнились: для клиента они ничем
+// не отличаются от новых. Вызывается под блокировкой не чаще раза в минуту.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if !now.Before(b.full) {
+			delete(l.buckets, key)
+		}
+	}
+}
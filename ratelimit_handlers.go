@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"take-a-pill/problem"
+	"take-a-pill/ratelimit"
+
+	"github.com/gorilla/mux"
+)
+
+// Заголовок с ключом API клиента
+const headerAPIKey = "X-API-Key"
+
+// rateLimit ограничивает частоту запросов каждого клиента к каждому маршруту.
+// Клиент определяется по ключу API из настроек, иначе - по IP адресу.
+func (s *Server) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Служебные маршруты опрашиваются мониторингом и не ограничиваются
+		if quietPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		route := rateLimitRoute(r)
+		limit, ok := s.cfg.RateLimits[route]
+		if !ok {
+			limit = s.cfg.DefaultRateLimit
+		}
+
+		allowed, wait := s.limiter.Allow(route+"|"+s.clientKey(r), ratelimit.Limit{Rate: limit.Rate, Burst: limit.Burst})
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			s.writeProblem(w, r, http.StatusTooManyRequests, problem.CodeRateLimited)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAdmin проверяет, что запрос отправлен с ключом API администратора
+func (s *Server) isAdmin(r *http.Request) bool {
+	key := r.Header.Get(headerAPIKey)
+	return s.cfg.AdminAPIKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(s.cfg.AdminAPIKey)) == 1
+}
+
+// knownAPIKey проверяет, что ключ API есть в настройках
+func (s *Server) knownAPIKey(key string) bool {
+	known := false
+	for _, candidate := range append([]string{s.cfg.AdminAPIKey}, s.cfg.APIKeys...) {
+		if candidate != "" && subtle.ConstantTimeCompare([]byte(key), []byte(candidate)) == 1 {
+			known = true
+		}
+	}
+	if _, ok := s.apiKeyUser(key); ok {
+		known = true
+	}
+	return known
+}
+
+// apiKeyUser возвращает пользователя, которому выдан ключ API
+func (s *Server) apiKeyUser(key string) (string, bool) {
+	userID, found := "", false
+	for candidate, user := range s.cfg.UserAPIKeys {
+		if key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(candidate)) == 1 {
+			userID, found = user, true
+		}
+	}
+	return userID, found
+}
+
+// rateLimitRoute возвращает метод и шаблон маршрута без версии API, чтобы
+// устаревшие маршруты и маршруты /v1 расходовали одни и те же токены
+func rateLimitRoute(r *http.Request) string {
+	path := r.URL.Path
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			path = template
+		}
+	}
+	return r.Method + " " + strings.TrimPrefix(path, "/v1")
+}
+
+// clientKey определяет клиента, которому принадлежит запрос. Ключ API и
+// user_id выбирает сам клиент, и с новым значением в каждом запросе он
+// получал бы новый лимит, поэтому учитываются только ключи из настроек.
+func (s *Server) clientKey(r *http.Request) string {
+	if key := r.Header.Get(headerAPIKey); key != "" && s.knownAPIKey(key) {
+		return "key:" + key
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// limitBody ограничивает размер тела запроса
+func (s *Server) limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.cfg.MaxBodyBytes > 0 && r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, s.cfg.MaxBodyBytes)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tooLarge проверяет, что тело запроса не удалось прочитать из-за ограничения размера
+func tooLarge(err error) (*http.MaxBytesError, bool) {
+	var maxBytesErr *http.MaxBytesError
+	ok := errors.As(err, &maxBytesErr)
+	return maxBytesErr, ok
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"take-a-pill/models"
+)
+
+// Обработчик для предварительного просмотра объединения напоминаний без сохранения.
+// ETag ответа нужно передать в If-Match, чтобы сохранить показанные изменения.
+func (s *Server) previewConsolidation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.consolidationUser(w, r)
+	if !ok {
+		return
+	}
+
+	consolidation, err := s.db.PreviewConsolidation(r.Context(), userID)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	s.writeConsolidation(w, consolidation)
+}
+
+// Обработчик для объединения напоминаний пользователя. Если курсы изменились
+// после просмотра, возвращает 412.
+func (s *Server) consolidateReminders(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.consolidationUser(w, r)
+	if !ok {
+		return
+	}
+
+	consolidation, err := s.db.ConsolidateReminders(r.Context(), userID, ifMatchTag(r))
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	s.writeConsolidation(w, consolidation)
+}
+
+// consolidationUser читает ID пользователя из запроса на объединение напоминаний.
+// При ошибке сам отправляет ответ клиенту и возвращает false.
+func (s *Server) consolidationUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var request models.ConsolidationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		s.writeInvalidJSON(w, r, err)
+		return "", false
+	}
+	if request.UserID == "" {
+		s.writeMissingParameter(w, r, "user_id")
+		return "", false
+	}
+	return request.UserID, true
+}
+
+// writeConsolidation отправляет напоминания до и после объединения в ответе
+func (s *Server) writeConsolidation(w http.ResponseWriter, consolidation *models.Consolidation) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", `"`+consolidation.Tag+`"`)
+	if err := json.NewEncoder(w).Encode(consolidation); err != nil {
+		s.logger.Warn("ошибка при отправке ответа", "error", err)
+	}
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"time"
+
+	"take-a-pill/models"
+	"take-a-pill/timing"
+	"take-a-pill/tracing"
+)
+
+// PreviewConsolidation показывает, как изменятся напоминания пользователя
+// после ConsolidateReminders, ничего не сохраняя. Tag результата нужно
+// передать в ConsolidateReminders, чтобы сохранить именно эти изменения.
+func (s *MemoryStorage) PreviewConsolidation(ctx context.Context, userID string) (*models.Consolidation, error) {
+	_, span := startSpan(ctx, "PreviewConsolidation", tracing.UserHash(userID))
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, consolidation := s.consolidate(userID, s.clock.Now())
+	return consolidation, nil
+}
+
+// ConsolidateReminders сдвигает автоматически рассчитанные времена приема
+// действующих курсов пользователя так, чтобы напоминаний за день было как
+// можно меньше. Частота и промежутки между приемами курса сохраняются,
+// курсы, привязанные к еде, и приостановленные курсы не сдвигаются.
+// tag - Tag из PreviewConsolidation, пустой - без проверки. Если с тех пор
+// курсы пользователя изменились, ничего не меняет и возвращает ошибку.
+func (s *MemoryStorage) ConsolidateReminders(ctx context.Context, userID, tag string) (*models.Consolidation, error) {
+	_, span := startSpan(ctx, "ConsolidateReminders", tracing.UserHash(userID))
+	defer span.End()
+
+	s.mu.Lock()
+	now := s.clock.Now()
+
+	courses, consolidation := s.consolidate(userID, now)
+	if tag != "" && tag != consolidation.Tag {
+		s.mu.Unlock()
+		return nil, preconditionFailed(CodeRemindersChanged)
+	}
+
+	updates := s.applyCourseTimes(courses, now)
+	if len(updates) > 0 {
+		s.reindexUser(userID)
+	}
+	consolidation.Applied = true
+	consolidation.Tag = coursesTag(courses)
+	s.mu.Unlock()
+
+	for _, event := range updates {
+		s.events.Publish(event)
+	}
+	return consolidation, nil
+}
+
+// consolidate рассчитывает объединение напоминаний пользователя. Возвращает
+// курсы с новыми временами приема и напоминания до и после объединения.
+// Вызывается под блокировкой.
+func (s *MemoryStorage) consolidate(userID string, now time.Time) ([]courseTimes, *models.Consolidation) {
+	courses := s.activeCourses(userID, now)
+	input := timingCourses(courses)
+	for i, c := range courses {
+		// Напоминания приостановленного курса не приходят, но после
+		// возобновления его приемы должны остаться на своих местах
+		if c.schedule.Paused {
+			input[i].Movable = false
+		}
+	}
+	result := timing.Consolidate(input, s.separationRules(courses), s.timingOptions(nil), s.cfg.ConsolidationMaxShift)
+	for i := range courses {
+		courses[i].times = result[i]
+	}
+
+	consolidation := &models.Consolidation{
+		Before:  reminders(courses, false),
+		After:   reminders(courses, true),
+		Changes: []models.TakingTimesChange{},
+		Tag:     coursesTag(courses),
+	}
+	for _, c := range courses {
+		if !slices.Equal(c.times, c.schedule.TakingTimes) {
+			consolidation.Changes = append(consolidation.Changes, models.TakingTimesChange{
+				ScheduleID:   c.schedule.ID,
+				MedicineName: c.schedule.MedicineName,
+				Before:       c.schedule.TakingTimes,
+				After:        c.times,
+			})
+		}
+	}
+	return courses, consolidation
+}
+
+// coursesTag возвращает метку состояния курсов: она меняется, если курс
+// добавился, закончился или изменилась версия любого из курсов
+func coursesTag(courses []courseTimes) string {
+	h := sha256.New()
+	for _, c := range courses {
+		fmt.Fprintf(h, "%s:%d;", c.schedule.ID, c.schedule.Version)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// reminders группирует приемы не приостановленных курсов по времени суток.
+// after выбирает новые времена приема вместо сохраненных.
+func reminders(courses []courseTimes, after bool) []models.Reminder {
+	byMinute := make(map[int]*models.Reminder)
+	for _, c := range courses {
+		if c.schedule.Paused {
+			continue
+		}
+		times := c.schedule.TakingTimes
+		if after {
+			times = c.times
+		}
+		for _, t := range times {
+			reminder, ok := byMinute[minuteOfDay(t)]
+			if !ok {
+				reminder = &models.Reminder{Time: t}
+				byMinute[minuteOfDay(t)] = reminder
+			}
+			reminder.MedicineNames = append(reminder.MedicineNames, c.schedule.MedicineName)
+		}
+	}
+
+	list := make([]models.Reminder, 0, len(byMinute))
+	for _, reminder := range byMinute {
+		list = append(list, *reminder)
+	}
+	slices.SortFunc(list, func(a, b models.Reminder) int {
+		return minuteOfDay(a.Time) - minuteOfDay(b.Time)
+	})
+	return list
+}
@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"strings"
+	"take-a-pill/models"
+	"time"
+
+	"take-a-pill/catalog"
+	"take-a-pill/i18n"
+)
+
+// Коды предупреждений о суточной дозе
+const (
+	// Суммарная суточная доза вещества превышает максимум
+	WarningDailyDoseLimit = "daily_dose_limit"
+	// Суточную дозу не удалось проверить: лекарства нет в справочнике
+	// или количество вещества нельзя перевести в единицы ограничения
+	WarningDoseLimitUnchecked = "dose_limit_unchecked"
+)
+
+// DoseExcess описывает превышение суточной дозы действующего вещества
+type DoseExcess struct {
+	// Действующее вещество
+	Ingredient string `json:"ingredient"`
+	// Суммарное количество вещества в сутки по всем курсам
+	Total float64 `json:"total"`
+	// Максимальное количество вещества в сутки
+	MaxDaily float64 `json:"max_daily"`
+	// Единица измерения
+	Unit string `json:"unit"`
+}
+
+func (e DoseExcess) String() string {
+	return e.Localize(i18n.Default)
+}
+
+// Localize возвращает описание превышения на заданном языке
+func (e DoseExcess) Localize(lang i18n.Lang) string {
+	return i18n.T(lang, CodeDailyDoseLimit, e.args()...)
+}
+
+// args возвращает аргументы для текста о превышении суточной дозы
+func (e DoseExcess) args() []any {
+	return []any{e.Ingredient, e.Total, e.Unit, e.MaxDaily, e.Unit}
+}
+
+// DoseLimitError возвращается, когда курс нельзя назначить из-за превышения суточной дозы
+type DoseLimitError struct {
+	Excesses []DoseExcess
+}
+
+func (e *DoseLimitError) Error() string {
+	return e.Localize(i18n.Default)
+}
+
+// Localize возвращает текст ошибки на заданном языке
+func (e *DoseLimitError) Localize(lang i18n.Lang) string {
+	var parts []string
+	for _, excess := range e.Excesses {
+		parts = append(parts, excess.Localize(lang))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// SetDoseLimits включает проверку суммарной суточной дозы действующих веществ
+func (s *MemoryStorage) SetDoseLimits(limits *catalog.DoseLimits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.doseLimits = limits
+}
+
+// doseCourse описывает курс для подсчета суточной дозы
+type doseCourse struct {
+	// ID лекарства из справочника
+	MedicineID string
+	// Название лекарства, по которому ищем его в справочнике без ID
+	MedicineName string
+	// Сколько приемов в день
+	DosesPerDay int
+	// Сколько таблеток за один прием
+	PillsPerDose int
+}
+
+// scheduleDoseCourse возвращает данные курса для подсчета суточной дозы
+func scheduleDoseCourse(schedule *models.Schedule) doseCourse {
+	course := doseCourse{
+		MedicineID:   schedule.MedicineID,
+		MedicineName: schedule.MedicineName,
+		DosesPerDay:  len(schedule.TakingTimes),
+		PillsPerDose: 1,
+	}
+	if schedule.Inventory != nil {
+		course.PillsPerDose = schedule.Inventory.PillsPerDose
+	}
+	return course
+}
+
+// checkDoseLimits суммирует суточное количество действующих веществ по всем
+// активным курсам пользователя вместе с новым курсом. Проверяются только
+// вещества нового курса. В зависимости от настроек превышение возвращается
+// как ошибка или как предупреждение. Курс без medicine_id ищется
+// в справочнике по точному названию, а если не найден - возвращается
+// предупреждение, что дозу проверить не удалось. Вызывается под блокировкой.
+func (s *MemoryStorage) checkDoseLimits(userID, excludeScheduleID string, course doseCourse, now time.Time) ([]models.Warning, error) {
+	if s.doseLimits == nil || s.catalog == nil {
+		return nil, nil
+	}
+
+	if s.courseMedicine(course) == nil {
+		return s.uncheckedDoseLimits(course.MedicineName), nil
+	}
+	ingredients := s.dailyIngredients(course)
+	if len(ingredients) == 0 {
+		return nil, nil
+	}
+
+	// Количества веществ с ограничением в единицах ограничения. Названия
+	// сравниваются без учета регистра, как в таблице суточных доз. Если
+	// количество нельзя перевести в единицы ограничения, сумма неизвестна.
+	totals := make(map[string]float64)
+	unchecked := make(map[string]bool)
+	add := func(ingredient catalog.Ingredient) {
+		key := catalog.IngredientKey(ingredient.Name)
+		limit, ok := s.doseLimits.Get(ingredient.Name)
+		if !ok {
+			return
+		}
+		amount, ok := catalog.ConvertAmount(ingredient.Amount, ingredient.Unit, limit.Unit)
+		if !ok {
+			unchecked[key] = true
+			return
+		}
+		totals[key] += amount
+	}
+	for _, ingredient := range ingredients {
+		add(ingredient)
+	}
+
+	for _, schedule := range s.userSchedules(userID) {
+		if schedule.ID == excludeScheduleID || !schedule.IsActive(now) {
+			continue
+		}
+		for _, ingredient := range s.dailyIngredients(scheduleDoseCourse(schedule)) {
+			// Учитываем только вещества нового курса
+			key := catalog.IngredientKey(ingredient.Name)
+			if _, ok := totals[key]; ok || unchecked[key] {
+				add(ingredient)
+			}
+		}
+	}
+
+	var excesses []DoseExcess
+	var warnings []models.Warning
+	checked := make(map[string]bool)
+	for _, ingredient := range ingredients {
+		key := catalog.IngredientKey(ingredient.Name)
+		if checked[key] {
+			continue
+		}
+		checked[key] = true
+
+		limit, ok := s.doseLimits.Get(ingredient.Name)
+		if !ok {
+			continue
+		}
+		if unchecked[key] {
+			warnings = append(warnings, uncheckedDoseWarning(ingredient.Name))
+			continue
+		}
+		if totals[key] > limit.MaxDaily {
+			excesses = append(excesses, DoseExcess{
+				Ingredient: ingredient.Name,
+				Total:      totals[key],
+				MaxDaily:   limit.MaxDaily,
+				Unit:       limit.Unit,
+			})
+		}
+	}
+
+	if len(excesses) == 0 {
+		return warnings, nil
+	}
+	if s.cfg.RejectOverDoseLimit {
+		return nil, &DoseLimitError{Excesses: excesses}
+	}
+
+	for _, excess := range excesses {
+		warnings = append(warnings, models.Warning{
+			Code:    WarningDailyDoseLimit,
+			Message: excess.String(),
+			Args:    excess.args(),
+		})
+	}
+	return warnings, nil
+}
+
+// uncheckedDoseLimits предупреждает, что суточную дозу лекарства, которого
+// нет в справочнике, проверить не удалось. Предупреждение нужно, только если
+// похожие лекарства справочника содержат вещества с ограничением.
+func (s *MemoryStorage) uncheckedDoseLimits(name string) []models.Warning {
+	for _, medicine := range s.catalog.Search(name, 0) {
+		for _, ingredient := range medicine.Ingredients {
+			if _, ok := s.doseLimits.Get(ingredient.Name); ok {
+				return []models.Warning{uncheckedDoseWarning(name)}
+			}
+		}
+	}
+	return nil
+}
+
+// uncheckedDoseWarning предупреждает, что суточную дозу name проверить не удалось
+func uncheckedDoseWarning(name string) models.Warning {
+	return models.Warning{
+		Code:    WarningDoseLimitUnchecked,
+		Message: i18n.T(i18n.Default, WarningDoseLimitUnchecked, name),
+		Args:    []any{name},
+	}
+}
+
+// courseMedicine возвращает лекарство курса из справочника: по ID, а если
+// ID не указан - по точному названию или синониму. nil, если не найдено.
+func (s *MemoryStorage) courseMedicine(course doseCourse) *catalog.Medicine {
+	if course.MedicineID != "" {
+		if medicine, err := s.catalog.Get(course.MedicineID); err == nil {
+			return medicine
+		}
+	}
+	if medicine, ok := s.catalog.FindByName(course.MedicineName); ok {
+		return medicine
+	}
+	return nil
+}
+
+// dailyIngredients возвращает суточное количество действующих веществ курса
+func (s *MemoryStorage) dailyIngredients(course doseCourse) []catalog.Ingredient {
+	medicine := s.courseMedicine(course)
+	if medicine == nil {
+		return nil
+	}
+
+	daily := make([]catalog.Ingredient, 0, len(medicine.Ingredients))
+	for _, ingredient := range medicine.Ingredients {
+		ingredient.Amount *= float64(course.DosesPerDay * course.PillsPerDose)
+		daily = append(daily, ingredient)
+	}
+	return daily
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"take-a-pill/events"
+	"take-a-pill/models"
+	"take-a-pill/tracing"
+)
+
+// PublishDueDoses публикует событие dose.due для каждого приема активных курсов,
+// время которого наступило в промежутке (from, to]. Возвращает число событий.
+// Приемы ищутся по индексам пользователей, где нет приостановленных курсов
+// и приемы отсортированы по времени суток, поэтому просматриваются только
+// приемы из промежутка, а не все расписания.
+func (s *MemoryStorage) PublishDueDoses(ctx context.Context, from, to time.Time) int {
+	_, span := startSpan(ctx, "PublishDueDoses")
+	defer span.End()
+
+	if !to.After(from) {
+		return 0
+	}
+
+	s.mu.RLock()
+	span.SetAttributes(tracing.ScheduleCount(len(s.schedules)))
+	var due []events.Event
+	s.users.Range(func(_, value any) bool {
+		occurrences := value.(*userIndex).occurrences
+		for day := startOfDay(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+			// Первый прием этого дня после from
+			i := sort.Search(len(occurrences), func(i int) bool {
+				return occurrenceTime(day, occurrences[i].at, to.Location()).After(from)
+			})
+			for ; i < len(occurrences); i++ {
+				o := occurrences[i]
+				at := occurrenceTime(day, o.at, to.Location())
+				if at.After(to) {
+					break
+				}
+				if o.activeAt(at) {
+					due = append(due, doseDueEvent(s.schedules[o.scheduleID], o.at, at))
+				}
+			}
+		}
+		return true
+	})
+	s.mu.RUnlock()
+
+	// Публикуем в порядке наступления приемов
+	sort.SliceStable(due, func(i, j int) bool {
+		if !due[i].Time.Equal(due[j].Time) {
+			return due[i].Time.Before(due[j].Time)
+		}
+		return due[i].ScheduleID < due[j].ScheduleID
+	})
+	for _, event := range due {
+		s.events.Publish(event)
+	}
+	return len(due)
+}
+
+// doseDueEvent готовит событие о наступлении времени приема.
+// Вызывается под блокировкой.
+func doseDueEvent(schedule *models.Schedule, t models.TakingTime, at time.Time) events.Event {
+	data := map[string]any{
+		"medicine_name": schedule.MedicineName,
+		"hour":          t.Hour,
+		"minute":        t.Minute,
+	}
+	if schedule.Inventory != nil {
+		data["pills_per_dose"] = schedule.Inventory.PillsPerDose
+	}
+
+	return events.Event{
+		Type:       events.TypeDoseDue,
+		UserID:     schedule.UserID,
+		ScheduleID: schedule.ID,
+		Time:       at,
+		Data:       data,
+	}
+}
+
+// occurrenceTime возвращает время приема в заданный день
+func occurrenceTime(day time.Time, t models.TakingTime, loc *time.Location) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour, t.Minute, 0, 0, loc)
+}
+
+// startOfDay возвращает полночь того же дня
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"errors"
+
+	"take-a-pill/i18n"
+)
+
+// Виды ошибок хранилища. Проверяются через errors.Is.
+var (
+	// Запрошенный объект не найден
+	ErrNotFound = errors.New("не найдено")
+	// Операция противоречит текущему состоянию объекта
+	ErrConflict = errors.New("конфликт")
+	// Запрос некорректен
+	ErrInvalid = errors.New("некорректный запрос")
+	// Нужный для операции сервис недоступен
+	ErrUnavailable = errors.New("сервис недоступен")
+	// Объект изменился с тех пор, как его прочитал клиент
+	ErrPreconditionFailed = errors.New("версия не совпадает")
+)
+
+// Коды ошибок хранилища
+const (
+	CodeScheduleNotFound     = "schedule_not_found"
+	CodeProfileNotFound      = "profile_not_found"
+	CodePrescriptionNotFound = "prescription_not_found"
+	CodePrescriptionExpired  = "prescription_expired"
+	CodePrescriptionMismatch = "prescription_mismatch"
+	CodeNoRefillsLeft        = "no_refills_left"
+	CodeInventoryNotSet      = "inventory_not_set"
+	CodePackSizeNotSet       = "pack_size_not_set"
+	CodeInsufficientStock    = "insufficient_stock"
+	CodeInvalidPacks         = "invalid_packs"
+	CodeMedicineNotFound     = "medicine_not_found"
+	CodeCatalogUnavailable   = "catalog_unavailable"
+	CodeStorageUnavailable   = "storage_unavailable"
+	CodeContraindicated      = "contraindicated"
+	CodeDailyDoseLimit       = "daily_dose_limit"
+	CodeInvalidParameter     = "invalid_parameter"
+	CodeVersionMismatch      = "version_mismatch"
+	CodeScheduleQuota        = "schedule_quota_exceeded"
+	CodeSeparationConflict   = "separation_conflict"
+	CodeRemindersChanged     = "reminders_changed"
+	CodeProfileChanged       = "profile_changed"
+)
+
+// Error описывает ошибку хранилища с машиночитаемым кодом
+type Error struct {
+	// Вид ошибки: ErrNotFound, ErrConflict, ErrInvalid, ErrUnavailable
+	// или ErrPreconditionFailed
+	Kind error
+	// Машиночитаемый код ошибки, он же ключ текста ошибки
+	Code string
+	// Аргументы для текста ошибки
+	Args []any
+}
+
+func (e *Error) Error() string {
+	return e.Localize(i18n.Default)
+}
+
+// Localize возвращает текст ошибки на заданном языке
+func (e *Error) Localize(lang i18n.Lang) string {
+	return i18n.T(lang, e.Code, e.Args...)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func notFound(code string, args ...any) error {
+	return &Error{Kind: ErrNotFound, Code: code, Args: args}
+}
+
+func conflict(code string, args ...any) error {
+	return &Error{Kind: ErrConflict, Code: code, Args: args}
+}
+
+func invalid(code string, args ...any) error {
+	return &Error{Kind: ErrInvalid, Code: code, Args: args}
+}
+
+func unavailable(code string, args ...any) error {
+	return &Error{Kind: ErrUnavailable, Code: code, Args: args}
+}
+
+func preconditionFailed(code string, args ...any) error {
+	return &Error{Kind: ErrPreconditionFailed, Code: code, Args: args}
+}
+
+// errScheduleNotFound возвращается, когда расписания нет в хранилище
+func errScheduleNotFound() error {
+	return notFound(CodeScheduleNotFound)
+}
+
+// errPrescriptionNotFound возвращается, когда рецепта нет в хранилище
+func errPrescriptionNotFound() error {
+	return notFound(CodePrescriptionNotFound)
+}
@@ -0,0 +1,24 @@
+package storage
+
+import "context"
+
+// Ping проверяет, что хранилище может обслуживать запросы: блокировка
+// хранилища освобождается до истечения контекста
+func (s *MemoryStorage) Ping(ctx context.Context) error {
+	ctx, span := startSpan(ctx, "Ping")
+	defer span.End()
+
+	done := make(chan struct{})
+	go func() {
+		s.mu.RLock()
+		s.mu.RUnlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return unavailable(CodeStorageUnavailable)
+	}
+}
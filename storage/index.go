@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"take-a-pill/models"
+)
+
+// userIndex хранит расписания одного пользователя и отсортированные по времени
+// суток приемы его курсов. Индекс не изменяется после создания: при изменении
+// расписаний пользователя строится новый, поэтому читать индекс можно
+// без блокировки хранилища.
+type userIndex struct {
+	// ID расписаний пользователя в порядке создания
+	scheduleIDs []string
+	// Приемы не приостановленных курсов, отсортированные по времени суток.
+	// При равном времени порядок совпадает с порядком создания расписаний.
+	occurrences []occurrence
+}
+
+// occurrence - ежедневный прием по расписанию
+type occurrence struct {
+	scheduleID   string
+	medicineName string
+	at           models.TakingTime
+	// Когда заканчивается курс, нулевое время - постоянный прием
+	endsAt time.Time
+}
+
+// activeAt проверяет, что курс еще идет в заданный момент
+func (o occurrence) activeAt(now time.Time) bool {
+	return o.endsAt.IsZero() || now.Before(o.endsAt)
+}
+
+// userIndex возвращает индекс пользователя или nil, если расписаний у него нет
+func (s *MemoryStorage) userIndex(userID string) *userIndex {
+	if idx, ok := s.users.Load(userID); ok {
+		return idx.(*userIndex)
+	}
+	return nil
+}
+
+// userSchedules возвращает расписания пользователя в порядке создания.
+// Вызывается под блокировкой.
+func (s *MemoryStorage) userSchedules(userID string) []*models.Schedule {
+	idx := s.userIndex(userID)
+	if idx == nil {
+		return nil
+	}
+
+	schedules := make([]*models.Schedule, 0, len(idx.scheduleIDs))
+	for _, id := range idx.scheduleIDs {
+		schedules = append(schedules, s.schedules[id])
+	}
+	return schedules
+}
+
+// indexSchedule добавляет новое расписание в индекс пользователя.
+// Вызывается под блокировкой на запись.
+func (s *MemoryStorage) indexSchedule(schedule *models.Schedule) {
+	var ids []string
+	if idx := s.userIndex(schedule.UserID); idx != nil {
+		ids = append(ids, idx.scheduleIDs...)
+	}
+	s.buildUserIndex(schedule.UserID, append(ids, schedule.ID))
+}
+
+// reindexUser перестраивает индекс пользователя после изменения его расписаний.
+// Вызывается под блокировкой на запись.
+func (s *MemoryStorage) reindexUser(userID string) {
+	if idx := s.userIndex(userID); idx != nil {
+		s.buildUserIndex(userID, idx.scheduleIDs)
+	}
+}
+
+// buildUserIndex строит индекс по расписаниям пользователя и заменяет им старый.
+// Вызывается под блокировкой на запись.
+func (s *MemoryStorage) buildUserIndex(userID string, scheduleIDs []string) {
+	idx := &userIndex{scheduleIDs: scheduleIDs}
+	for _, id := range scheduleIDs {
+		schedule := s.schedules[id]
+		if schedule.Paused {
+			continue
+		}
+
+		var endsAt time.Time
+		if schedule.Duration > 0 {
+			endsAt = schedule.CreatedAt.AddDate(0, 0, schedule.Duration)
+		}
+		for _, t := range schedule.TakingTimes {
+			idx.occurrences = append(idx.occurrences, occurrence{
+				scheduleID:   schedule.ID,
+				medicineName: schedule.MedicineName,
+				at:           t,
+				endsAt:       endsAt,
+			})
+		}
+	}
+
+	sort.SliceStable(idx.occurrences, func(i, j int) bool {
+		return minuteOfDay(idx.occurrences[i].at) < minuteOfDay(idx.occurrences[j].at)
+	})
+	s.users.Store(userID, idx)
+}
+
+// minuteOfDay возвращает номер минуты от начала суток
+func minuteOfDay(t models.TakingTime) int {
+	return t.Hour*60 + t.Minute
+}
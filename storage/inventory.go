@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"take-a-pill/models"
+	"time"
+
+	"take-a-pill/events"
+	"take-a-pill/tracing"
+	"take-a-pill/validation"
+)
+
+// normalizeInventory подставляет значения по умолчанию для запаса таблеток
+func normalizeInventory(inv models.Inventory) models.Inventory {
+	if inv.PillsPerDose == 0 {
+		inv.PillsPerDose = 1
+	}
+	inv.LowStockNotified = false
+	return inv
+}
+
+// SetInventory задает запас таблеток для расписания.
+// version - ожидаемая версия расписания, 0 - без проверки.
+func (s *MemoryStorage) SetInventory(ctx context.Context, scheduleID string, version int, inv models.Inventory) (*models.InventoryForecast, error) {
+	_, span := startSpan(ctx, "SetInventory", tracing.ScheduleID(scheduleID))
+	defer span.End()
+
+	if err := validation.ValidateInventory(&inv); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	schedule, err := s.scheduleForUpdate(scheduleID, version)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	inventory := normalizeInventory(inv)
+
+	// Количество таблеток за прием влияет на суточную дозу веществ
+	course := scheduleDoseCourse(schedule)
+	course.PillsPerDose = inventory.PillsPerDose
+	warnings, err := s.checkDoseLimits(schedule.UserID, schedule.ID, course, s.clock.Now())
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	schedule.Inventory = &inventory
+	schedule.Version++
+	updated := scheduleEvent(events.TypeScheduleUpdated, schedule, s.clock.Now())
+	forecast, event := s.checkLowStock(schedule, s.clock.Now())
+	forecast.Warnings = warnings
+	s.mu.Unlock()
+
+	s.events.Publish(updated)
+	if event != nil {
+		s.events.Publish(*event)
+	}
+	return forecast, nil
+}
+
+// RefillInventory добавляет к запасу заданное количество упаковок.
+// version - ожидаемая версия расписания, 0 - без проверки.
+func (s *MemoryStorage) RefillInventory(ctx context.Context, scheduleID string, version int, packs int) (*models.InventoryForecast, error) {
+	_, span := startSpan(ctx, "RefillInventory", tracing.ScheduleID(scheduleID))
+	defer span.End()
+
+	if packs < 0 {
+		return nil, invalid(CodeInvalidPacks)
+	}
+	if packs == 0 {
+		packs = 1
+	}
+
+	s.mu.Lock()
+	schedule, err := s.scheduleForUpdate(scheduleID, version)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	if schedule.Inventory == nil || schedule.Inventory.PackSize == 0 {
+		s.mu.Unlock()
+		return nil, conflict(CodePackSizeNotSet)
+	}
+
+	// Запас заменяется целиком, чтобы не менять значение, которое уже отдали
+	inventory := *schedule.Inventory
+	inventory.PillsOnHand += packs * inventory.PackSize
+	schedule.Inventory = &inventory
+	schedule.Version++
+	updated := scheduleEvent(events.TypeScheduleUpdated, schedule, s.clock.Now())
+	forecast, event := s.checkLowStock(schedule, s.clock.Now())
+	s.mu.Unlock()
+
+	s.events.Publish(updated)
+	if event != nil {
+		s.events.Publish(*event)
+	}
+	return forecast, nil
+}
+
+// LogDose отмечает прием лекарства и уменьшает запас таблеток.
+// version - ожидаемая версия расписания, 0 - без проверки.
+func (s *MemoryStorage) LogDose(ctx context.Context, scheduleID string, version int) (*models.InventoryForecast, error) {
+	_, span := startSpan(ctx, "LogDose", tracing.ScheduleID(scheduleID))
+	defer span.End()
+
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	schedule, err := s.scheduleForUpdate(scheduleID, version)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	var forecast *models.InventoryForecast
+	var lowStock *events.Event
+	if schedule.Inventory != nil {
+		if schedule.Inventory.PillsOnHand < schedule.Inventory.PillsPerDose {
+			s.mu.Unlock()
+			return nil, conflict(CodeInsufficientStock)
+		}
+		inventory := *schedule.Inventory
+		inventory.PillsOnHand -= inventory.PillsPerDose
+		schedule.Inventory = &inventory
+		schedule.Version++
+		forecast, lowStock = s.checkLowStock(schedule, now)
+	}
+
+	taken := events.Event{
+		Type:       events.TypeDoseTaken,
+		UserID:     schedule.UserID,
+		ScheduleID: schedule.ID,
+		Time:       now,
+		Data: map[string]any{
+			"medicine_name": schedule.MedicineName,
+		},
+	}
+	s.mu.Unlock()
+
+	s.events.Publish(taken)
+	if lowStock != nil {
+		s.events.Publish(*lowStock)
+	}
+	return forecast, nil
+}
+
+// ForecastInventory рассчитывает, когда закончится запас таблеток
+func (s *MemoryStorage) ForecastInventory(ctx context.Context, scheduleID string) (*models.InventoryForecast, error) {
+	_, span := startSpan(ctx, "ForecastInventory", tracing.ScheduleID(scheduleID))
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	schedule, ok := s.schedules[scheduleID]
+	if !ok {
+		return nil, errScheduleNotFound()
+	}
+	if schedule.Inventory == nil {
+		return nil, notFound(CodeInventoryNotSet)
+	}
+
+	return s.forecast(schedule, s.clock.Now()), nil
+}
+
+// CheckLowStock проверяет запас по всем действующим курсам и публикует
+// inventory.low_stock для тех, о которых еще не предупреждали: например,
+// если запаса мало уже при создании расписания. Возвращает число событий.
+// Курсы просматриваются под блокировкой на чтение, блокировка на запись
+// берется, только если у какого-то курса изменился признак предупреждения.
+func (s *MemoryStorage) CheckLowStock(ctx context.Context) int {
+	_, span := startSpan(ctx, "CheckLowStock")
+	defer span.End()
+
+	now := s.clock.Now()
+	changed := s.lowStockChanges(now)
+	span.SetAttributes(tracing.ScheduleCount(len(changed)))
+	if len(changed) == 0 {
+		return 0
+	}
+
+	s.mu.Lock()
+	var lowStock []events.Event
+	for _, id := range changed {
+		// Курс могли изменить, пока блокировка была снята
+		schedule, ok := s.schedules[id]
+		if !ok || schedule.Inventory == nil || schedule.Status(now) != models.StatusActive {
+			continue
+		}
+		if _, event := s.checkLowStock(schedule, now); event != nil {
+			lowStock = append(lowStock, *event)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(lowStock, func(i, j int) bool {
+		return lowStock[i].ScheduleID < lowStock[j].ScheduleID
+	})
+	for _, event := range lowStock {
+		s.events.Publish(event)
+	}
+	return len(lowStock)
+}
+
+// lowStockChanges возвращает ID действующих курсов, у которых признак
+// предупреждения о запасе не совпадает с прогнозом
+func (s *MemoryStorage) lowStockChanges(now time.Time) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var changed []string
+	s.users.Range(func(_, value any) bool {
+		for _, id := range value.(*userIndex).scheduleIDs {
+			schedule := s.schedules[id]
+			if schedule.Inventory == nil || schedule.Status(now) != models.StatusActive {
+				continue
+			}
+			if s.forecast(schedule, now).LowStock != schedule.Inventory.LowStockNotified {
+				changed = append(changed, id)
+			}
+		}
+		return true
+	})
+	return changed
+}
+
+// checkLowStock считает прогноз и готовит событие о заканчивающемся запасе,
+// если о нем еще не предупреждали. Вызывается под блокировкой на запись.
+func (s *MemoryStorage) checkLowStock(schedule *models.Schedule, now time.Time) (*models.InventoryForecast, *events.Event) {
+	forecast := s.forecast(schedule, now)
+	notified := schedule.Inventory.LowStockNotified
+	if notified != forecast.LowStock {
+		inventory := *schedule.Inventory
+		inventory.LowStockNotified = forecast.LowStock
+		schedule.Inventory = &inventory
+	}
+	if !forecast.LowStock || notified {
+		return forecast, nil
+	}
+
+	return forecast, &events.Event{
+		Type:       events.TypeLowStock,
+		UserID:     schedule.UserID,
+		ScheduleID: schedule.ID,
+		Time:       now,
+		Data: map[string]any{
+			"medicine_name": schedule.MedicineName,
+			"pills_on_hand": schedule.Inventory.PillsOnHand,
+			"days_left":     forecast.DaysLeft,
+			"run_out_date":  forecast.RunOutDate,
+		},
+	}
+}
+
+// forecast рассчитывает дату окончания запаса исходя из частоты приема
+func (s *MemoryStorage) forecast(schedule *models.Schedule, now time.Time) *models.InventoryForecast {
+	inv := *schedule.Inventory
+	forecast := &models.InventoryForecast{
+		ScheduleID:       schedule.ID,
+		ScheduleVersion:  schedule.Version,
+		Inventory:        inv,
+		DailyConsumption: len(schedule.TakingTimes) * inv.PillsPerDose,
+	}
+
+	// Если таблетки не расходуются, запас не закончится
+	if forecast.DailyConsumption == 0 {
+		forecast.EnoughForCourse = true
+		return forecast
+	}
+
+	forecast.DaysLeft = inv.PillsOnHand / forecast.DailyConsumption
+	runOut := now.AddDate(0, 0, forecast.DaysLeft)
+	forecast.RunOutDate = runOut.Format("2006-01-02")
+
+	// Для курса с ограниченной длительностью проверяем, хватит ли запаса до конца
+	if schedule.Duration > 0 {
+		endDate := schedule.CreatedAt.AddDate(0, 0, schedule.Duration)
+		forecast.EnoughForCourse = !runOut.Before(endDate)
+	}
+
+	forecast.LowStock = !forecast.EnoughForCourse && forecast.DaysLeft <= s.cfg.LowStockDays
+	return forecast
+}
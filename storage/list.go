@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+	"take-a-pill/models"
+
+	"take-a-pill/tracing"
+)
+
+// Ограничения размера страницы списка расписаний
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// Поля, по которым можно сортировать список расписаний
+const (
+	SortCreatedAt    = "created_at"
+	SortMedicineName = "medicine_name"
+)
+
+// ListOptions задает фильтры, сортировку и страницу списка расписаний
+type ListOptions struct {
+	// Состояние расписания: active, expired или paused (пусто - любое)
+	Status string
+	// Часть названия лекарства без учета регистра
+	Medicine string
+	// Поле сортировки; "-" в начале означает обратный порядок
+	Sort string
+	// Размер страницы
+	Limit int
+	// Курсор из предыдущей страницы
+	Cursor string
+}
+
+// listCursor указывает на последнее расписание предыдущей страницы
+type listCursor struct {
+	// Значение поля сортировки
+	Key string `json:"k"`
+	// ID расписания
+	ID string `json:"id"`
+}
+
+// ListSchedules возвращает страницу расписаний пользователя и курсор следующей
+// страницы. При равных значениях поля сортировки порядок определяется ID,
+// поэтому страницы стабильны между запросами.
+func (s *MemoryStorage) ListSchedules(ctx context.Context, userID string, opts ListOptions) ([]*models.Schedule, string, error) {
+	_, span := startSpan(ctx, "ListSchedules", tracing.UserHash(userID))
+	defer span.End()
+
+	field, desc := strings.CutPrefix(opts.Sort, "-")
+	if field == "" {
+		field = SortCreatedAt
+	}
+	if field != SortCreatedAt && field != SortMedicineName {
+		return nil, "", invalid(CodeInvalidParameter, "sort")
+	}
+
+	switch opts.Status {
+	case "", models.StatusActive, models.StatusExpired, models.StatusPaused:
+	default:
+		return nil, "", invalid(CodeInvalidParameter, "status")
+	}
+
+	limit := opts.Limit
+	if limit == 0 {
+		limit = DefaultListLimit
+	}
+	if limit < 0 || limit > MaxListLimit {
+		return nil, "", invalid(CodeInvalidParameter, "limit")
+	}
+
+	var cursor *listCursor
+	if opts.Cursor != "" {
+		var err error
+		cursor, err = decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, "", invalid(CodeInvalidParameter, "cursor")
+		}
+	}
+
+	now := s.clock.Now()
+	medicine := strings.ToLower(opts.Medicine)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type item struct {
+		schedule *models.Schedule
+		key      string
+	}
+
+	var items []item
+	for _, schedule := range s.userSchedules(userID) {
+		if opts.Status != "" && schedule.Status(now) != opts.Status {
+			continue
+		}
+		if medicine != "" && !strings.Contains(strings.ToLower(schedule.MedicineName), medicine) {
+			continue
+		}
+		items = append(items, item{schedule: schedule, key: sortKey(schedule, field)})
+	}
+	span.SetAttributes(tracing.ScheduleCount(len(items)))
+
+	// less сравнивает пары (значение поля, ID) с учетом направления сортировки
+	less := func(keyA, idA, keyB, idB string) bool {
+		if keyA != keyB {
+			return (keyA < keyB) != desc
+		}
+		return (idA < idB) != desc
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return less(items[i].key, items[i].schedule.ID, items[j].key, items[j].schedule.ID)
+	})
+
+	// Пропускаем все расписания до курсора включительно
+	start := 0
+	if cursor != nil {
+		start = sort.Search(len(items), func(i int) bool {
+			return less(cursor.Key, cursor.ID, items[i].key, items[i].schedule.ID)
+		})
+	}
+
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+
+	page := make([]*models.Schedule, 0, end-start)
+	for _, it := range items[start:end] {
+		// Отдаем копии: после снятия блокировки расписания могут изменить
+		page = append(page, it.schedule.Clone())
+	}
+
+	var next string
+	if end < len(items) {
+		last := items[end-1]
+		next = encodeCursor(listCursor{Key: last.key, ID: last.schedule.ID})
+	}
+
+	return page, next, nil
+}
+
+// sortKey возвращает значение поля сортировки в виде строки, которую можно
+// сравнивать лексикографически
+func sortKey(schedule *models.Schedule, field string) string {
+	if field == SortMedicineName {
+		return strings.ToLower(schedule.MedicineName)
+	}
+	return schedule.CreatedAt.UTC().Format("2006-01-02T15:04:05.000000000Z")
+}
+
+func encodeCursor(c listCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var c listCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
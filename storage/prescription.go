@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"take-a-pill/models"
+
+	"take-a-pill/i18n"
+	"take-a-pill/tracing"
+	"take-a-pill/validation"
+
+	"github.com/google/uuid"
+)
+
+// Коды предупреждений, связанных с рецептами
+const (
+	// Курс длится дольше, чем действует рецепт
+	WarningCourseOutlivesPrescription = "course_outlives_prescription"
+)
+
+// CreatePrescription создает новый рецепт
+func (s *MemoryStorage) CreatePrescription(ctx context.Context, req *models.PrescriptionRequest) (*models.Prescription, error) {
+	_, span := startSpan(ctx, "CreatePrescription", tracing.UserHash(req.UserID))
+	defer span.End()
+
+	if err := validation.ValidatePrescriptionRequest(req); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prescription := &models.Prescription{
+		ID:               uuid.New().String(),
+		UserID:           req.UserID,
+		MedicineName:     req.MedicineName,
+		Prescriber:       req.Prescriber,
+		IssueDate:        req.IssueDate,
+		ValidUntil:       req.ValidUntil,
+		RefillsRemaining: req.Refills,
+		ScheduleIDs:      []string{},
+		CreatedAt:        s.clock.Now(),
+	}
+
+	s.prescriptions[prescription.ID] = prescription
+
+	return prescription.Clone(), nil
+}
+
+// GetPrescriptionByID возвращает рецепт по его ID
+func (s *MemoryStorage) GetPrescriptionByID(ctx context.Context, prescriptionID string) (*models.Prescription, error) {
+	_, span := startSpan(ctx, "GetPrescriptionByID")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Отдаем копию: после снятия блокировки к рецепту могут добавиться расписания
+	if prescription, ok := s.prescriptions[prescriptionID]; ok {
+		return prescription.Clone(), nil
+	}
+	return nil, errPrescriptionNotFound()
+}
+
+// GetPrescriptionsByUserID возвращает список ID рецептов пользователя
+// в порядке создания, при одинаковом времени - по ID
+func (s *MemoryStorage) GetPrescriptionsByUserID(ctx context.Context, userID string) []string {
+	_, span := startSpan(ctx, "GetPrescriptionsByUserID", tracing.UserHash(userID))
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var prescriptions []*models.Prescription
+	for _, prescription := range s.prescriptions {
+		if prescription.UserID == userID {
+			prescriptions = append(prescriptions, prescription)
+		}
+	}
+	sort.Slice(prescriptions, func(i, j int) bool {
+		a, b := prescriptions[i], prescriptions[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+
+	prescriptionIDs := make([]string, 0, len(prescriptions))
+	for _, prescription := range prescriptions {
+		prescriptionIDs = append(prescriptionIDs, prescription.ID)
+	}
+	return prescriptionIDs
+}
+
+// UsePrescriptionRefill списывает одну повторную выдачу по рецепту
+func (s *MemoryStorage) UsePrescriptionRefill(ctx context.Context, prescriptionID string) (*models.Prescription, error) {
+	_, span := startSpan(ctx, "UsePrescriptionRefill")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prescription, ok := s.prescriptions[prescriptionID]
+	if !ok {
+		return nil, errPrescriptionNotFound()
+	}
+
+	if s.clock.Now().After(prescription.ValidUntil) {
+		return nil, conflict(CodePrescriptionExpired)
+	}
+
+	if prescription.RefillsRemaining == 0 {
+		return nil, conflict(CodeNoRefillsLeft)
+	}
+
+	prescription.RefillsRemaining--
+
+	return prescription.Clone(), nil
+}
+
+// prescriptionForSchedule проверяет рецепт, по которому создается расписание,
+// и подставляет из него название лекарства. Вызывается под блокировкой на запись.
+func (s *MemoryStorage) prescriptionForSchedule(req *models.ScheduleRequest) (*models.Prescription, error) {
+	prescription, ok := s.prescriptions[req.PrescriptionID]
+	if !ok || prescription.UserID != req.UserID {
+		return nil, errPrescriptionNotFound()
+	}
+
+	if s.clock.Now().After(prescription.ValidUntil) {
+		return nil, conflict(CodePrescriptionExpired)
+	}
+
+	if req.MedicineName == "" {
+		req.MedicineName = prescription.MedicineName
+	} else if !strings.EqualFold(req.MedicineName, prescription.MedicineName) {
+		return nil, invalid(CodePrescriptionMismatch)
+	}
+
+	return prescription, nil
+}
+
+// courseOutlivesPrescription возвращает предупреждение, если курс закончится
+// позже, чем истечет срок действия рецепта
+func courseOutlivesPrescription(schedule *models.Schedule, prescription *models.Prescription) *models.Warning {
+	if schedule.Duration > 0 {
+		endDate := schedule.CreatedAt.AddDate(0, 0, schedule.Duration)
+		if !endDate.After(prescription.ValidUntil) {
+			return nil
+		}
+	}
+
+	args := []any{prescription.ValidUntil.Format("2006-01-02")}
+	return &models.Warning{
+		Code:    WarningCourseOutlivesPrescription,
+		Message: i18n.T(i18n.Default, WarningCourseOutlivesPrescription, args...),
+		Args:    args,
+	}
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"take-a-pill/models"
+	"take-a-pill/tracing"
+	"take-a-pill/validation"
+)
+
+// PreviewSchedule проверяет запрос и рассчитывает расписание так же, как при
+// создании, но ничего не сохраняет. Возвращает времена приема, первый и
+// последний прием и сколько всего будет приемов за курс.
+func (s *MemoryStorage) PreviewSchedule(ctx context.Context, req *models.ScheduleRequest) (*models.SchedulePreview, error) {
+	if req == nil {
+		return nil, validation.ValidateScheduleRequest(req)
+	}
+
+	_, span := startSpan(ctx, "PreviewSchedule", tracing.UserHash(req.UserID))
+	defer span.End()
+
+	// Запрос дополняется названием лекарства, поэтому работаем с копией
+	copied := *req
+
+	s.mu.RLock()
+	d, err := s.prepareSchedule(&copied)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	schedule := d.schedule
+
+	preview := &models.SchedulePreview{
+		MedicineName: schedule.MedicineName,
+		Frequency:    schedule.Frequency,
+		Duration:     schedule.Duration,
+		TakingTimes:  schedule.TakingTimes,
+		Warnings:     d.warnings,
+	}
+	if len(schedule.TakingTimes) == 0 {
+		return preview, nil
+	}
+
+	preview.FirstDose = firstDose(schedule.TakingTimes, schedule.CreatedAt)
+	if schedule.Duration > 0 {
+		last := lastDose(schedule.TakingTimes, schedule.CreatedAt.AddDate(0, 0, schedule.Duration))
+		preview.LastDose = &last
+		// Курс длится целое число суток, поэтому каждое время приема
+		// встречается в нем ровно Duration раз
+		preview.TotalDoses = schedule.Duration * len(schedule.TakingTimes)
+	}
+	return preview, nil
+}
+
+// firstDose возвращает первый прием не раньше момента from.
+// Времена приема должны быть отсортированы.
+func firstDose(times []models.TakingTime, from time.Time) time.Time {
+	day := startOfDay(from)
+	for _, t := range times {
+		if at := doseTime(day, t); !at.Before(from) {
+			return at
+		}
+	}
+	return doseTime(day.AddDate(0, 0, 1), times[0])
+}
+
+// lastDose возвращает последний прием раньше момента окончания курса.
+// Времена приема должны быть отсортированы.
+func lastDose(times []models.TakingTime, endsAt time.Time) time.Time {
+	day := startOfDay(endsAt)
+	for i := len(times) - 1; i >= 0; i-- {
+		if at := doseTime(day, times[i]); at.Before(endsAt) {
+			return at
+		}
+	}
+	return doseTime(day.AddDate(0, 0, -1), times[len(times)-1])
+}
+
+// doseTime возвращает момент приема в заданный день
+func doseTime(day time.Time, t models.TakingTime) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour, t.Minute, 0, 0, day.Location())
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"maps"
+	"take-a-pill/models"
+
+	"take-a-pill/events"
+	"take-a-pill/tracing"
+	"take-a-pill/validation"
+)
+
+// SetProfile сохраняет настройки пользователя. Если изменилось время еды,
+// пересчитываются времена приема курсов, которые от него зависят.
+// Если version не 0, настройки сохраняются только при совпадении версии.
+func (s *MemoryStorage) SetProfile(ctx context.Context, profile *models.UserProfile, version int) (*models.UserProfile, error) {
+	_, span := startSpan(ctx, "SetProfile", tracing.UserHash(profile.UserID))
+	defer span.End()
+
+	if err := validation.ValidateProfile(profile); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	previous, existed := s.profiles[profile.UserID]
+	current := 0
+	if existed {
+		current = previous.Version
+	}
+	if version != 0 && version != current {
+		s.mu.Unlock()
+		return nil, preconditionFailed(CodeProfileChanged, current)
+	}
+
+	saved := *profile
+	saved.Meals = maps.Clone(profile.Meals)
+	saved.Version = current + 1
+	s.profiles[profile.UserID] = &saved
+
+	var updates []events.Event
+	if !existed || !maps.Equal(previous.Meals, saved.Meals) {
+		var err error
+		updates, err = s.rescheduleMeals(profile.UserID)
+		if err != nil {
+			// Возвращаем прежние настройки, курсы не изменились
+			if existed {
+				s.profiles[profile.UserID] = previous
+			} else {
+				delete(s.profiles, profile.UserID)
+			}
+			s.mu.Unlock()
+			return nil, err
+		}
+	}
+	s.mu.Unlock()
+
+	for _, event := range updates {
+		s.events.Publish(event)
+	}
+	copied := saved
+	return &copied, nil
+}
+
+// GetProfile возвращает настройки пользователя
+func (s *MemoryStorage) GetProfile(ctx context.Context, userID string) (*models.UserProfile, error) {
+	_, span := startSpan(ctx, "GetProfile", tracing.UserHash(userID))
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if profile, ok := s.profiles[userID]; ok {
+		copied := *profile
+		return &copied, nil
+	}
+	return nil, notFound(CodeProfileNotFound)
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"take-a-pill/events"
+	"take-a-pill/i18n"
+	"take-a-pill/interactions"
+	"take-a-pill/models"
+	"take-a-pill/timing"
+)
+
+// courseTimes - курс и его новые, еще не сохраненные времена приема
+type courseTimes struct {
+	schedule *models.Schedule
+	times    []models.TakingTime
+}
+
+// activeCourses возвращает действующие курсы пользователя с их текущими
+// временами приема. Вызывается под блокировкой.
+func (s *MemoryStorage) activeCourses(userID string, now time.Time) []courseTimes {
+	var courses []courseTimes
+	for _, schedule := range s.userSchedules(userID) {
+		if schedule.IsActive(now) {
+			courses = append(courses, courseTimes{schedule: schedule, times: schedule.TakingTimes})
+		}
+	}
+	return courses
+}
+
+// separateDoses сдвигает времена приема курсов так, чтобы между приемами
+// лекарств был промежуток, который требует таблица взаимодействий. Курсы,
+// привязанные к еде, не сдвигаются. Новые времена записываются в courses,
+// при ошибке courses не меняются. Вызывается под блокировкой.
+func (s *MemoryStorage) separateDoses(courses []courseTimes) error {
+	rules := s.separationRules(courses)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	result, err := timing.Separate(timingCourses(courses), rules, s.timingOptions(nil))
+	var separationErr *timing.SeparationError
+	if errors.As(err, &separationErr) {
+		rule := separationErr.Rule
+		return conflict(CodeSeparationConflict,
+			courses[rule.A].schedule.MedicineName, courses[rule.B].schedule.MedicineName, int(rule.Separation/time.Minute))
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := range courses {
+		courses[i].times = result[i]
+	}
+	return nil
+}
+
+// separationRules возвращает промежутки, на которые нужно разнести приемы
+// курсов по таблице взаимодействий. Вызывается под блокировкой.
+func (s *MemoryStorage) separationRules(courses []courseTimes) []timing.Rule {
+	if s.interactions == nil || len(courses) < 2 {
+		return nil
+	}
+
+	medicines := make([]interactions.Medicine, len(courses))
+	for i, c := range courses {
+		medicines[i] = s.interactionMedicine(c.schedule.MedicineName, c.schedule.MedicineID)
+	}
+	var rules []timing.Rule
+	for i := range courses {
+		for j := i + 1; j < len(courses); j++ {
+			if separation := s.interactions.Separation(medicines[i], medicines[j]); separation > 0 {
+				rules = append(rules, timing.Rule{A: i, B: j, Separation: separation})
+			}
+		}
+	}
+	return rules
+}
+
+// timingCourses описывает курсы для сдвига времен приема. Курсы, привязанные
+// к еде, сдвигать нельзя.
+func timingCourses(courses []courseTimes) []timing.Course {
+	input := make([]timing.Course, len(courses))
+	for i, c := range courses {
+		input[i] = timing.Course{Times: c.times, Movable: !dependsOnMeals(c.schedule)}
+	}
+	return input
+}
+
+// applyCourseTimes сохраняет новые времена приема курсов и возвращает события
+// об их изменении для публикации после снятия блокировки. Индекс пользователя
+// нужно перестроить отдельно. Вызывается под блокировкой на запись.
+func (s *MemoryStorage) applyCourseTimes(courses []courseTimes, now time.Time) []events.Event {
+	var updates []events.Event
+	for _, c := range courses {
+		if slices.Equal(c.times, c.schedule.TakingTimes) {
+			continue
+		}
+		c.schedule.TakingTimes = c.times
+		c.schedule.Version++
+		updates = append(updates, scheduleEvent(events.TypeScheduleUpdated, c.schedule, now))
+	}
+	return updates
+}
+
+// shiftWarning возвращает предупреждение, если времена приема курса сдвинуты
+func shiftWarning(c courseTimes) *models.Warning {
+	if len(c.times) == 0 || slices.Equal(c.times, c.schedule.TakingTimes) {
+		return nil
+	}
+
+	// Курс сдвигается целиком, поэтому достаточно сравнить первые приемы
+	args := []any{c.schedule.MedicineName, minuteOfDay(c.times[0]) - minuteOfDay(c.schedule.TakingTimes[0])}
+	return &models.Warning{
+		Code:    WarningDosesShifted,
+		Message: i18n.T(i18n.Default, WarningDosesShifted, args...),
+		Args:    args,
+	}
+}
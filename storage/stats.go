@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"time"
+
+	"take-a-pill/models"
+)
+
+// Stats - сводка по содержимому хранилища для мониторинга
+type Stats struct {
+	// Всего расписаний
+	Schedules int
+	// Расписаний, по которым сейчас идет прием
+	ActiveSchedules int
+	// Пользователей, у которых есть хотя бы одно расписание
+	Users int
+	// Пользователей, у которых есть хотя бы одно активное расписание
+	ActiveUsers int
+}
+
+// Stats возвращает сводку по расписаниям и пользователям
+func (s *MemoryStorage) Stats() Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.clock.Now()
+	users := make(map[string]bool)
+	stats := Stats{Schedules: len(s.schedules)}
+	for _, schedule := range s.schedules {
+		active := schedule.Status(now) == models.StatusActive
+		if active {
+			stats.ActiveSchedules++
+		}
+		// Пользователь активен, если активно хотя бы одно его расписание
+		users[schedule.UserID] = users[schedule.UserID] || active
+	}
+
+	stats.Users = len(users)
+	for _, active := range users {
+		if active {
+			stats.ActiveUsers++
+		}
+	}
+	return stats
+}
+
+// SetNextTakingsObserver задает функцию, которой сообщается,
+// сколько времени заняло вычисление ближайших приемов
+func (s *MemoryStorage) SetNextTakingsObserver(observe func(time.Duration)) {
+	s.nextTakingsObserver.Store(&observe)
+}
+
+// observeNextTakings сообщает время вычисления ближайших приемов
+func (s *MemoryStorage) observeNextTakings(start time.Time) {
+	if observe := s.nextTakingsObserver.Load(); observe != nil && *observe != nil {
+		(*observe)(time.Since(start))
+	}
+}
@@ -1,180 +1,474 @@
 package storage
 
 import (
-	"fmt"
-	"log"
+	"context"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"take-a-pill/models"
 	"time"
 
+	"take-a-pill/catalog"
+	"take-a-pill/clock"
+	"take-a-pill/config"
+	"take-a-pill/events"
+	"take-a-pill/i18n"
+	"take-a-pill/interactions"
+	"take-a-pill/tracing"
 	"take-a-pill/validation"
 
 	"github.com/google/uuid"
 )
 
+// Коды предупреждений при создании расписания
+const (
+	// Лекарство взаимодействует с уже назначенным
+	WarningDrugInteraction = "drug_interaction"
+	// Времена приема сдвинуты, чтобы разнести их с другим лекарством
+	WarningDosesShifted = "doses_shifted"
+)
+
 // Структура для хранения расписаний в памяти
 type MemoryStorage struct {
 	// Карта для хранения расписаний, где ключ - это ID расписания
 	schedules map[string]*models.Schedule
+	// Карта для хранения рецептов, где ключ - это ID рецепта
+	prescriptions map[string]*models.Prescription
+	// Карта для хранения настроек пользователей, где ключ - это ID пользователя
+	profiles map[string]*models.UserProfile
 	// Мьютекс для безопасной работы с картой
 	mu sync.RWMutex
+	// Настройки сервиса
+	cfg *config.Config
+	// Шина событий хранилища
+	events *events.Bus
+	// Проверка взаимодействия лекарств (может отсутствовать)
+	interactions *interactions.Checker
+	// Справочник лекарств (может отсутствовать)
+	catalog *catalog.Catalog
+	// Максимальные суточные дозы веществ (могут отсутствовать)
+	doseLimits *catalog.DoseLimits
+	// Часы, по которым считаются приемы и сроки курсов
+	clock clock.Clock
+	// Индексы расписаний по пользователям: ID пользователя -> *userIndex
+	users sync.Map
+	// Получает время вычисления ближайших приемов (может отсутствовать)
+	nextTakingsObserver atomic.Pointer[func(time.Duration)]
 }
 
-// Создаем новое хранилище
+// Создаем новое хранилище с настройками по умолчанию
 func NewMemoryStorage() *MemoryStorage {
+	return NewMemoryStorageWithConfig(config.DefaultConfig())
+}
+
+// NewMemoryStorageWithConfig создает новое хранилище с заданными настройками
+func NewMemoryStorageWithConfig(cfg *config.Config) *MemoryStorage {
+	return NewMemoryStorageWithClock(cfg, clock.System{})
+}
+
+// NewMemoryStorageWithClock создает хранилище, которое берет текущее время
+// из заданных часов
+func NewMemoryStorageWithClock(cfg *config.Config, c clock.Clock) *MemoryStorage {
 	return &MemoryStorage{
-		schedules: make(map[string]*models.Schedule),
+		schedules:     make(map[string]*models.Schedule),
+		prescriptions: make(map[string]*models.Prescription),
+		profiles:      make(map[string]*models.UserProfile),
+		cfg:           cfg,
+		events:        events.NewBus(),
+		clock:         c,
 	}
 }
 
-// Создаем новое расписание
-func (s *MemoryStorage) CreateSchedule(req *models.ScheduleRequest) (*models.Schedule, error) {
-	// Валидация запроса
-	if err := validation.ValidateScheduleRequest(req); err != nil {
-		return nil, err
+// SetInteractionChecker включает проверку взаимодействия лекарств при создании расписаний
+func (s *MemoryStorage) SetInteractionChecker(checker *interactions.Checker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.interactions = checker
+}
+
+// SetCatalog подключает справочник лекарств, на который могут ссылаться расписания
+func (s *MemoryStorage) SetCatalog(c *catalog.Catalog) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.catalog = c
+}
+
+// Events возвращает шину событий хранилища
+func (s *MemoryStorage) Events() *events.Bus {
+	return s.events
+}
+
+// Создаем новое расписание. Вместе с расписанием возвращаются предупреждения,
+// которые не мешают его создать.
+func (s *MemoryStorage) CreateSchedule(ctx context.Context, req *models.ScheduleRequest) (*models.Schedule, []models.Warning, error) {
+	_, span := startSpan(ctx, "CreateSchedule", tracing.UserHash(req.UserID))
+	defer span.End()
+
+	schedule, warnings, updates, err := s.createSchedule(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.events.Publish(scheduleEvent(events.TypeScheduleCreated, schedule, schedule.CreatedAt))
+	for _, event := range updates {
+		s.events.Publish(event)
+	}
+	return schedule, warnings, nil
+}
+
+// createSchedule проверяет запрос и сохраняет расписание. Возвращает также
+// события об изменении курсов, времена приема которых пришлось сдвинуть.
+func (s *MemoryStorage) createSchedule(req *models.ScheduleRequest) (*models.Schedule, []models.Warning, []events.Event, error) {
+	if req == nil {
+		return nil, nil, nil, validation.ValidateScheduleRequest(req)
 	}
 
 	// Блокируем доступ к карте для записи
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Создаем новое расписание
-	schedule := &models.Schedule{
-		ID:           uuid.New().String(),
-		UserID:       req.UserID,
-		MedicineName: req.MedicineName,
-		Frequency:    req.Frequency,
-		Duration:     req.Duration,
-		CreatedAt:    time.Now(),
-		TakingTimes:  models.CalculateTakingTimes(req.Frequency),
+	d, err := s.prepareSchedule(req)
+	if err != nil {
+		return nil, nil, nil, err
 	}
+	schedule := d.schedule
+
+	if d.prescription != nil {
+		d.prescription.ScheduleIDs = append(d.prescription.ScheduleIDs, schedule.ID)
+	}
+
+	// Сдвигаем приемы других курсов пользователя
+	updates := s.applyCourseTimes(d.shifted, schedule.CreatedAt)
 
-	// Сохраняем расписание в карту
+	// Сохраняем расписание в карту и в индекс пользователя
 	s.schedules[schedule.ID] = schedule
+	s.indexSchedule(schedule)
 
-	return schedule, nil
+	return schedule.Clone(), d.warnings, updates, nil
 }
 
-// GetSchedulesByUserID возвращает список ID расписаний пользователя
-func (s *MemoryStorage) GetSchedulesByUserID(userID string) []string {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// draft - проверенное и рассчитанное, но еще не сохраненное расписание
+type draft struct {
+	schedule *models.Schedule
+	// Рецепт, по которому назначен курс
+	prescription *models.Prescription
+	// Другие курсы пользователя, приемы которых нужно сдвинуть
+	shifted  []courseTimes
+	warnings []models.Warning
+}
 
-	var scheduleIDs []string
-	for id, schedule := range s.schedules {
-		if schedule.UserID == userID {
-			scheduleIDs = append(scheduleIDs, id)
+// prepareSchedule проверяет запрос и рассчитывает расписание, ничего не сохраняя.
+// Вызывается под блокировкой.
+func (s *MemoryStorage) prepareSchedule(req *models.ScheduleRequest) (*draft, error) {
+	// Если курс назначен по рецепту, берем из него название лекарства
+	var prescription *models.Prescription
+	if req.PrescriptionID != "" {
+		var err error
+		prescription, err = s.prescriptionForSchedule(req)
+		if err != nil {
+			return nil, err
 		}
 	}
-	return scheduleIDs
-}
 
-// GetScheduleByID возвращает расписание по его ID
-func (s *MemoryStorage) GetScheduleByID(scheduleID string) (*models.Schedule, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	// Если указано лекарство из справочника, берем из него название
+	if req.MedicineID != "" {
+		medicine, err := s.catalogMedicine(req.MedicineID)
+		if err != nil {
+			return nil, err
+		}
+		if req.MedicineName == "" {
+			req.MedicineName = medicine.Name
+		}
+	}
 
-	if schedule, ok := s.schedules[scheduleID]; ok {
-		return schedule, nil
+	// Для приемов относительно еды частоту можно не указывать
+	if req.Frequency == 0 {
+		req.Frequency = len(req.MealDoses)
 	}
-	return nil, fmt.Errorf("расписание не найдено")
-}
 
-// CalculateTakingTimes рассчитывает времена приёма лекарств
-func (s *MemoryStorage) CalculateTakingTimes(schedule *models.Schedule) []models.TakingTime {
-	dayStart := 8 // 8:00
-	dayEnd := 22  // 22:00
-	totalHours := dayEnd - dayStart
+	// Валидация запроса
+	if err := validation.ValidateScheduleRequest(req); err != nil {
+		return nil, err
+	}
 
-	// Рассчитываем интервал между приёмами в часах
-	interval := totalHours / schedule.Frequency
-	if interval < 1 {
-		interval = 1 // Минимальный интервал - 1 час
+	// Проверяем, что пользователь не превысил число действующих расписаний
+	if err := s.checkScheduleQuota(req.UserID); err != nil {
+		return nil, err
 	}
 
-	var times []models.TakingTime
-	for hour := dayStart; hour <= dayEnd-interval; hour += interval {
-		// Округляем минуты до ближайших 15
-		minute := 0
-		times = append(times, models.TakingTime{
-			Hour:   hour,
-			Minute: minute,
-		})
+	// Рассчитываем времена приема
+	takingTimes, distribution, err := s.takingTimes(req.UserID, req.Distribution, req.Frequency, req.MealDoses)
+	if err != nil {
+		return nil, err
+	}
+
+	// Создаем новое расписание
+	schedule := &models.Schedule{
+		ID:             uuid.New().String(),
+		UserID:         req.UserID,
+		MedicineName:   req.MedicineName,
+		Frequency:      req.Frequency,
+		Duration:       req.Duration,
+		CreatedAt:      s.clock.Now(),
+		TakingTimes:    takingTimes,
+		Distribution:   distribution,
+		MealDoses:      req.MealDoses,
+		PrescriptionID: req.PrescriptionID,
+		MedicineID:     req.MedicineID,
+		Version:        1,
+	}
+
+	if req.Inventory != nil {
+		inventory := normalizeInventory(*req.Inventory)
+		schedule.Inventory = &inventory
+	}
+
+	// Проверяем взаимодействие с уже назначенными лекарствами
+	warnings, err := s.checkInteractions(req.UserID, s.interactionMedicine(req.MedicineName, req.MedicineID), schedule.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем суммарную суточную дозу действующих веществ
+	doseWarnings, err := s.checkDoseLimits(req.UserID, "", scheduleDoseCourse(schedule), schedule.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	warnings = append(warnings, doseWarnings...)
+
+	// Разносим приемы с лекарствами, которые нельзя принимать одновременно
+	courses := append(s.activeCourses(req.UserID, schedule.CreatedAt), courseTimes{schedule: schedule, times: schedule.TakingTimes})
+	if err := s.separateDoses(courses); err != nil {
+		return nil, err
+	}
+	var shifted []courseTimes
+	for _, c := range courses {
+		if w := shiftWarning(c); w != nil {
+			warnings = append(warnings, *w)
+			if c.schedule != schedule {
+				shifted = append(shifted, c)
+			}
+		}
+	}
+	schedule.TakingTimes = courses[len(courses)-1].times
+
+	if prescription != nil {
+		if w := courseOutlivesPrescription(schedule, prescription); w != nil {
+			warnings = append(warnings, *w)
+		}
 	}
 
-	return times
+	return &draft{schedule: schedule, prescription: prescription, shifted: shifted, warnings: warnings}, nil
 }
 
-// GetNextTakings возвращает ближайшие приёмы лекарств для пользователя
-func (s *MemoryStorage) GetNextTakings(userID string) []models.NextTaking {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// checkScheduleQuota проверяет, что пользователь может создать еще одно
+// расписание. Завершенные курсы не учитываются. Вызывается под блокировкой.
+func (s *MemoryStorage) checkScheduleQuota(userID string) error {
+	if s.cfg.MaxSchedulesPerUser <= 0 {
+		return nil
+	}
 
-	var nextTakings []models.NextTaking
-	now := time.Now()
-	log.Printf("=== Начало GetNextTakings ===")
-	log.Printf("Текущее время: %v", now.Format("15:04:05"))
-
-	for id, schedule := range s.schedules {
-		log.Printf("\nПроверка расписания %s:", id)
-		log.Printf("- Пользователь: %s", schedule.UserID)
-		log.Printf("- Лекарство: %s", schedule.MedicineName)
-		log.Printf("- Частота: %d раз в день", schedule.Frequency)
-		log.Printf("- Длительность: %d дней", schedule.Duration)
-		log.Printf("- Создано: %v", schedule.CreatedAt.Format("2006-01-02 15:04:05"))
-		log.Printf("- Времена приема: %v", schedule.TakingTimes)
-
-		if schedule.UserID != userID {
-			log.Printf("Пропускаем: расписание принадлежит другому пользователю")
-			continue
+	now := s.clock.Now()
+	count := 0
+	for _, schedule := range s.userSchedules(userID) {
+		if schedule.IsActive(now) {
+			count++
 		}
+	}
+	if count >= s.cfg.MaxSchedulesPerUser {
+		return conflict(CodeScheduleQuota, s.cfg.MaxSchedulesPerUser)
+	}
+	return nil
+}
 
-		// Проверяем, не истек ли срок действия расписания
-		endDate := schedule.CreatedAt.AddDate(0, 0, schedule.Duration)
-		if now.After(endDate) {
-			log.Printf("Пропускаем: расписание истекло (конец: %v)", endDate.Format("2006-01-02 15:04:05"))
-			continue
+// checkInteractions проверяет взаимодействие лекарства с активными курсами
+// пользователя. Противопоказанное сочетание возвращается как ошибка,
+// остальные - как предупреждения. Вызывается под блокировкой.
+func (s *MemoryStorage) checkInteractions(userID string, medicine interactions.Medicine, now time.Time) ([]models.Warning, error) {
+	if s.interactions == nil {
+		return nil, nil
+	}
+
+	var others []interactions.Medicine
+	for _, schedule := range s.userSchedules(userID) {
+		if schedule.IsActive(now) {
+			others = append(others, s.interactionMedicine(schedule.MedicineName, schedule.MedicineID))
 		}
+	}
+
+	findings := s.interactions.Check(medicine, others)
+	if err := interactions.Contraindicated(findings); err != nil {
+		return nil, err
+	}
 
-		for _, t := range schedule.TakingTimes {
-			// Создаем время приема на сегодня
-			takingTime := time.Date(now.Year(), now.Month(), now.Day(), t.Hour, t.Minute, 0, 0, now.Location())
+	var warnings []models.Warning
+	for _, f := range findings {
+		args := []any{f.Medicine, f.Other, f.Description}
+		warnings = append(warnings, models.Warning{
+			Code:     WarningDrugInteraction,
+			Message:  i18n.T(i18n.Default, WarningDrugInteraction, args...),
+			Severity: string(f.Severity),
+			Args:     args,
+		})
+	}
+	return warnings, nil
+}
 
-			log.Printf("\nПроверка времени приема %v:", takingTime.Format("15:04"))
+// catalogMedicine возвращает лекарство из справочника по ID
+func (s *MemoryStorage) catalogMedicine(medicineID string) (*catalog.Medicine, error) {
+	if s.catalog == nil {
+		return nil, unavailable(CodeCatalogUnavailable)
+	}
 
-			// Если время уже прошло, пропускаем
-			if now.After(takingTime) {
-				log.Printf("- Пропускаем: время уже прошло")
-				continue
-			}
+	medicine, err := s.catalog.Get(medicineID)
+	if err != nil {
+		return nil, invalid(CodeMedicineNotFound)
+	}
+	return medicine, nil
+}
 
-			log.Printf("- Добавляем в список приемов")
-			nextTakings = append(nextTakings, models.NextTaking{
-				ScheduleID:     id,
-				MedicineName:   schedule.MedicineName,
-				NextTakingTime: t,
-			})
+// interactionMedicine описывает лекарство для проверки взаимодействий.
+// Для лекарств из справочника используются их действующие вещества.
+func (s *MemoryStorage) interactionMedicine(name, medicineID string) interactions.Medicine {
+	medicine := interactions.Medicine{Name: name}
+	if medicineID != "" && s.catalog != nil {
+		if m, err := s.catalog.Get(medicineID); err == nil {
+			medicine.Ingredients = m.IngredientNames()
 		}
 	}
+	return medicine
+}
 
-	// Сортируем по времени приема
-	sort.Slice(nextTakings, func(i, j int) bool {
-		timeI := time.Date(now.Year(), now.Month(), now.Day(),
-			nextTakings[i].NextTakingTime.Hour,
-			nextTakings[i].NextTakingTime.Minute, 0, 0, now.Location())
-		timeJ := time.Date(now.Year(), now.Month(), now.Day(),
-			nextTakings[j].NextTakingTime.Hour,
-			nextTakings[j].NextTakingTime.Minute, 0, 0, now.Location())
-		return timeI.Before(timeJ)
-	})
+// GetSchedulesByUserID возвращает список ID расписаний пользователя в порядке создания
+func (s *MemoryStorage) GetSchedulesByUserID(ctx context.Context, userID string) []string {
+	_, span := startSpan(ctx, "GetSchedulesByUserID", tracing.UserHash(userID))
+	defer span.End()
+
+	idx := s.userIndex(userID)
+	if idx == nil {
+		span.SetAttributes(tracing.ScheduleCount(0))
+		return nil
+	}
+	span.SetAttributes(tracing.ScheduleCount(len(idx.scheduleIDs)))
+
+	// Отдаем копию, чтобы вызывающий код не мог изменить индекс
+	return append([]string(nil), idx.scheduleIDs...)
+}
+
+// SetPaused приостанавливает или возобновляет курс.
+// version - ожидаемая версия расписания, 0 - без проверки.
+func (s *MemoryStorage) SetPaused(ctx context.Context, scheduleID string, version int, paused bool) (*models.Schedule, error) {
+	_, span := startSpan(ctx, "SetPaused", tracing.ScheduleID(scheduleID))
+	defer span.End()
+
+	s.mu.Lock()
+	schedule, err := s.scheduleForUpdate(scheduleID, version)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
 
-	log.Printf("\n=== Результаты ===")
-	log.Printf("Всего найдено приемов: %d", len(nextTakings))
-	for _, t := range nextTakings {
-		log.Printf("- %s в %02d:%02d", t.MedicineName, t.NextTakingTime.Hour, t.NextTakingTime.Minute)
+	changed := schedule.Paused != paused
+	if changed {
+		schedule.Paused = paused
+		schedule.Version++
+		s.reindexUser(schedule.UserID)
 	}
-	log.Printf("=== Конец GetNextTakings ===\n")
+	event := scheduleEvent(events.TypeScheduleUpdated, schedule, s.clock.Now())
+	schedule = schedule.Clone()
+	s.mu.Unlock()
 
+	if changed {
+		s.events.Publish(event)
+	}
+	return schedule, nil
+}
+
+// scheduleEvent готовит событие об изменении расписания.
+// Вызывается под блокировкой.
+func scheduleEvent(eventType string, schedule *models.Schedule, now time.Time) events.Event {
+	return events.Event{
+		Type:       eventType,
+		UserID:     schedule.UserID,
+		ScheduleID: schedule.ID,
+		Time:       now,
+		Data: map[string]any{
+			"medicine_name": schedule.MedicineName,
+			"version":       schedule.Version,
+			"status":        schedule.Status(now),
+		},
+	}
+}
+
+// scheduleForUpdate находит расписание для изменения и проверяет,
+// что его версия совпадает с ожидаемой. Вызывается под блокировкой.
+func (s *MemoryStorage) scheduleForUpdate(scheduleID string, version int) (*models.Schedule, error) {
+	schedule, ok := s.schedules[scheduleID]
+	if !ok {
+		return nil, errScheduleNotFound()
+	}
+	if version != 0 && schedule.Version != version {
+		return nil, preconditionFailed(CodeVersionMismatch, schedule.Version)
+	}
+	return schedule, nil
+}
+
+// GetScheduleByID возвращает расписание по его ID
+func (s *MemoryStorage) GetScheduleByID(ctx context.Context, scheduleID string) (*models.Schedule, error) {
+	_, span := startSpan(ctx, "GetScheduleByID", tracing.ScheduleID(scheduleID))
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Отдаем копию: после снятия блокировки расписание могут изменить
+	if schedule, ok := s.schedules[scheduleID]; ok {
+		return schedule.Clone(), nil
+	}
+	return nil, errScheduleNotFound()
+}
+
+// GetNextTakings возвращает ближайшие приёмы лекарств для пользователя
+func (s *MemoryStorage) GetNextTakings(ctx context.Context, userID string) []models.NextTaking {
+	return s.GetNextTakingsAt(ctx, userID, s.clock.Now())
+}
+
+// GetNextTakingsAt возвращает приемы, оставшиеся до конца суток, как если бы
+// сейчас было время now. Приемы берутся из индекса пользователя, поэтому
+// поиск не блокирует хранилище и не зависит от числа других пользователей.
+func (s *MemoryStorage) GetNextTakingsAt(ctx context.Context, userID string, now time.Time) []models.NextTaking {
+	_, span := startSpan(ctx, "GetNextTakings", tracing.UserHash(userID))
+	defer span.End()
+	defer s.observeNextTakings(time.Now())
+
+	idx := s.userIndex(userID)
+	if idx == nil {
+		span.SetAttributes(tracing.ScheduleCount(0))
+		return nil
+	}
+	span.SetAttributes(tracing.ScheduleCount(len(idx.scheduleIDs)))
+
+	// Приемы отсортированы по времени, поэтому находим первый еще не прошедший
+	takingTime := func(t models.TakingTime) time.Time {
+		return time.Date(now.Year(), now.Month(), now.Day(), t.Hour, t.Minute, 0, 0, now.Location())
+	}
+	first := sort.Search(len(idx.occurrences), func(i int) bool {
+		return !now.After(takingTime(idx.occurrences[i].at))
+	})
+
+	var nextTakings []models.NextTaking
+	for _, o := range idx.occurrences[first:] {
+		// Пропускаем завершенные курсы
+		if !o.activeAt(now) {
+			continue
+		}
+		nextTakings = append(nextTakings, models.NextTaking{
+			ScheduleID:     o.scheduleID,
+			MedicineName:   o.medicineName,
+			NextTakingTime: o.at,
+		})
+	}
 	return nextTakings
 }
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"errors"
+	"maps"
+	"sort"
+	"time"
+
+	"take-a-pill/events"
+	"take-a-pill/models"
+	"take-a-pill/timing"
+	"take-a-pill/validation"
+)
+
+// takingTimes рассчитывает времена приема курса пользователя. Для приемов
+// относительно еды времена считаются от времени еды пользователя, иначе -
+// заданным способом распределения (пустой - способ из настроек).
+// Возвращает способ, которым распределены приемы. Вызывается под блокировкой.
+func (s *MemoryStorage) takingTimes(userID, strategy string, frequency int, mealDoses []models.MealDose) ([]models.TakingTime, string, error) {
+	meals := s.mealTimes(userID)
+	if len(mealDoses) > 0 {
+		times, err := mealDoseTimes(mealDoses, meals)
+		return times, "", err
+	}
+
+	if strategy == "" {
+		strategy = s.cfg.TakingTimesStrategy
+	}
+
+	distributor, err := timing.New(strategy, s.timingOptions(meals))
+	if err != nil {
+		return nil, "", err
+	}
+
+	times, err := distributor.Distribute(frequency)
+	if errors.Is(err, timing.ErrTooFrequent) {
+		return nil, "", validation.FieldErr("frequency", validation.CodeTooFrequent)
+	}
+	return times, strategy, err
+}
+
+// timingOptions возвращает дневное окно и шаг приемов из настроек
+// и время еды пользователя
+func (s *MemoryStorage) timingOptions(meals map[string]time.Duration) timing.Options {
+	opts := timing.Options{
+		DayStart:    time.Duration(s.cfg.DayStartHour) * time.Hour,
+		DayEnd:      time.Duration(s.cfg.DayEndHour) * time.Hour,
+		Granularity: s.cfg.TakingTimesGranularity,
+	}
+	for _, meal := range models.Meals {
+		if at, ok := meals[meal]; ok {
+			opts.Meals = append(opts.Meals, at)
+		}
+	}
+	return opts
+}
+
+// mealTimes возвращает время еды пользователя от полуночи. Для приемов пищи,
+// которые пользователь не указал в настройках, берется время из настроек
+// сервиса. Вызывается под блокировкой.
+func (s *MemoryStorage) mealTimes(userID string) map[string]time.Duration {
+	meals := maps.Clone(s.cfg.MealTimes)
+	if meals == nil {
+		meals = make(map[string]time.Duration)
+	}
+	if profile, ok := s.profiles[userID]; ok {
+		for meal, t := range profile.Meals {
+			meals[meal] = time.Duration(t.Hour)*time.Hour + time.Duration(t.Minute)*time.Minute
+		}
+	}
+	return meals
+}
+
+// mealDoseTimes рассчитывает времена приемов относительно еды.
+// Времена должны быть разными и не выходить за пределы суток.
+func mealDoseTimes(doses []models.MealDose, meals map[string]time.Duration) ([]models.TakingTime, error) {
+	times := make([]models.TakingTime, 0, len(doses))
+	for _, dose := range doses {
+		at, ok := meals[dose.Meal]
+		if !ok {
+			return nil, validation.FieldErr("meal_doses", validation.CodeInvalid)
+		}
+
+		offset := time.Duration(dose.OffsetMinutes) * time.Minute
+		switch dose.Relation {
+		case models.MealRelationBefore:
+			at -= offset
+		case models.MealRelationAfter:
+			at += offset
+		}
+		if at < 0 || at >= 24*time.Hour {
+			return nil, validation.FieldErr("meal_doses", validation.CodeInvalid)
+		}
+
+		minutes := int(at / time.Minute)
+		times = append(times, models.TakingTime{Hour: minutes / 60, Minute: minutes % 60})
+	}
+
+	sort.Slice(times, func(i, j int) bool {
+		return minuteOfDay(times[i]) < minuteOfDay(times[j])
+	})
+	for i := 1; i < len(times); i++ {
+		if times[i] == times[i-1] {
+			return nil, validation.FieldErr("meal_doses", validation.CodeInvalid)
+		}
+	}
+	return times, nil
+}
+
+// dependsOnMeals проверяет, зависят ли времена приема курса от времени еды
+func dependsOnMeals(schedule *models.Schedule) bool {
+	return len(schedule.MealDoses) > 0 || schedule.Distribution == timing.StrategyMealAnchored
+}
+
+// rescheduleMeals пересчитывает времена приема действующих курсов пользователя,
+// которые зависят от времени еды, и разносит их с другими лекарствами. Если
+// хотя бы один курс пересчитать нельзя, ничего не меняет и возвращает ошибку.
+// Возвращает события об изменении курсов для публикации после снятия
+// блокировки. Вызывается под блокировкой на запись.
+func (s *MemoryStorage) rescheduleMeals(userID string) ([]events.Event, error) {
+	now := s.clock.Now()
+
+	courses := s.activeCourses(userID, now)
+	for i, c := range courses {
+		if !dependsOnMeals(c.schedule) {
+			continue
+		}
+		times, _, err := s.takingTimes(userID, c.schedule.Distribution, c.schedule.Frequency, c.schedule.MealDoses)
+		if err != nil {
+			return nil, err
+		}
+		courses[i].times = times
+	}
+	if err := s.separateDoses(courses); err != nil {
+		return nil, err
+	}
+
+	updates := s.applyCourseTimes(courses, now)
+	if len(updates) > 0 {
+		s.reindexUser(userID)
+	}
+	return updates, nil
+}
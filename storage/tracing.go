@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Трассировщик операций хранилища. Пока провайдер трассировок не настроен,
+// спаны не записываются.
+var tracer = otel.Tracer("take-a-pill/storage")
+
+// startSpan начинает спан операции хранилища
+func startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "storage."+operation, trace.WithAttributes(attrs...))
+}
@@ -0,0 +1,121 @@
+package timing
+
+import (
+	"sort"
+	"time"
+
+	"take-a-pill/models"
+)
+
+// Сколько раз пытаться улучшить найденное объединение напоминаний
+const maxConsolidateRounds = 10
+
+// Consolidate сдвигает времена приема курсов так, чтобы разных моментов
+// приема за день было как можно меньше. Курс сдвигается целиком, поэтому
+// частота и промежутки между его приемами сохраняются, а промежутки из
+// правил и дневное окно соблюдаются. maxShift ограничивает сдвиг курса
+// от исходных времен, 0 - без ограничений. Если объединить напоминания
+// не удается, возвращает исходные времена.
+//
+// Сначала курсы по очереди ставятся туда, где добавляют меньше всего новых
+// моментов приема (курсы с большим числом приемов раньше), затем каждый курс
+// пробуется передвинуть, пока это уменьшает число моментов.
+func Consolidate(courses []Course, rules []Rule, opts Options, maxShift time.Duration) [][]models.TakingTime {
+	s := newSeparator(courses, rules, opts)
+	limit := int(maxShift / time.Minute)
+
+	// Сначала курсы, которые нельзя сдвигать, затем курсы с большим числом приемов
+	var movable []int
+	for i, course := range courses {
+		if course.Movable {
+			movable = append(movable, i)
+		} else {
+			s.order = append(s.order, i)
+		}
+	}
+	sort.SliceStable(movable, func(a, b int) bool {
+		return len(courses[movable[a]].Times) > len(courses[movable[b]].Times)
+	})
+	s.order = append(s.order, movable...)
+
+	for _, i := range s.order {
+		others := s.moments(i)
+		offset, _, ok := s.bestOffset(i, limit, others)
+		if !ok {
+			return s.original()
+		}
+		s.offsets[i] = offset
+		s.placed[i] = true
+	}
+
+	for round := 0; round < maxConsolidateRounds; round++ {
+		improved := false
+		for _, i := range movable {
+			current := s.offsets[i]
+			others := s.moments(i)
+			currentAdded := s.added(i, current, others)
+
+			offset, added, ok := s.bestOffset(i, limit, others)
+			if ok && added < currentAdded {
+				s.offsets[i] = offset
+				improved = true
+			} else {
+				s.offsets[i] = current
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+
+	return s.result()
+}
+
+// bestOffset находит допустимый сдвиг курса i, который добавляет меньше всего
+// новых моментов приема к others. Из равных выбирается наименьший сдвиг.
+func (s *separator) bestOffset(i, limit int, others map[int]bool) (offset, added int, ok bool) {
+	for _, candidate := range s.candidates(s.courses[i]) {
+		if limit > 0 && (candidate > limit || candidate < -limit) {
+			continue
+		}
+		s.offsets[i] = candidate
+		if _, bad := s.violated(i); bad {
+			continue
+		}
+		if n := s.added(i, candidate, others); !ok || n < added {
+			offset, added, ok = candidate, n, true
+		}
+	}
+	return offset, added, ok
+}
+
+// moments возвращает моменты приема размещенных курсов, кроме курса except
+func (s *separator) moments(except int) map[int]bool {
+	moments := make(map[int]bool)
+	for j, course := range s.courses {
+		if j == except || !s.placed[j] {
+			continue
+		}
+		for _, t := range course.Times {
+			moments[minuteOf(t)+s.offsets[j]] = true
+		}
+	}
+	return moments
+}
+
+// added считает, сколько новых моментов приема добавит курс i со сдвигом offset
+func (s *separator) added(i, offset int, moments map[int]bool) int {
+	count := 0
+	for _, t := range s.courses[i].Times {
+		if !moments[minuteOf(t)+offset] {
+			count++
+		}
+	}
+	return count
+}
+
+// original возвращает исходные времена приема курсов
+func (s *separator) original() [][]models.TakingTime {
+	clear(s.offsets)
+	return s.result()
+}
@@ -0,0 +1,215 @@
+package timing
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"take-a-pill/models"
+)
+
+// Сколько вариантов сдвига перебирать, прежде чем сдаться
+const maxSeparateSteps = 100000
+
+// Course - курс лекарства, приемы которого нужно разнести с другими
+type Course struct {
+	// Времена приема по возрастанию
+	Times []models.TakingTime
+	// Можно ли сдвигать времена приема курса. Сдвигаются все приемы курса
+	// сразу, поэтому промежутки между ними не меняются.
+	Movable bool
+}
+
+// Rule - минимальный промежуток между любыми приемами двух курсов
+type Rule struct {
+	// Номера курсов
+	A, B int
+	// Минимальный промежуток
+	Separation time.Duration
+}
+
+// SeparationError возвращается, когда приемы курсов нельзя разнести
+// в пределах дневного окна
+type SeparationError struct {
+	// Правило, которое не удалось соблюсти
+	Rule Rule
+}
+
+func (e *SeparationError) Error() string {
+	return fmt.Sprintf("не удалось разнести приемы курсов %d и %d на %s", e.Rule.A, e.Rule.B, e.Rule.Separation)
+}
+
+// Separate сдвигает времена приема курсов так, чтобы между приемами курсов
+// из каждого правила был нужный промежуток, а приемы не выходили за дневное
+// окно. Предпочитаются наименьшие сдвиги курсов, стоящих раньше в списке,
+// поэтому уже назначенные курсы стоит передавать первыми. Возвращает новые
+// времена приема в том же порядке или SeparationError.
+func Separate(courses []Course, rules []Rule, opts Options) ([][]models.TakingTime, error) {
+	s := newSeparator(courses, rules, opts)
+
+	// Сначала курсы, которые нельзя сдвигать, затем остальные в исходном порядке
+	for i, course := range courses {
+		if !course.Movable {
+			s.order = append(s.order, i)
+		}
+	}
+	for i, course := range courses {
+		if course.Movable {
+			s.order = append(s.order, i)
+		}
+	}
+
+	if !s.place(0) {
+		return nil, &SeparationError{Rule: s.failed}
+	}
+	return s.result(), nil
+}
+
+// separator перебирает сдвиги курсов с возвратом
+type separator struct {
+	grid
+	courses []Course
+	rules   []Rule
+	// Порядок, в котором подбираются сдвиги
+	order []int
+	// Выбранные сдвиги в минутах
+	offsets []int
+	placed  []bool
+	steps   int
+	// Правило, на котором остановился самый глубокий неудачный перебор
+	failed Rule
+	depth  int
+}
+
+// newSeparator готовит перебор сдвигов курсов, пропуская пустые правила
+func newSeparator(courses []Course, rules []Rule, opts Options) *separator {
+	s := &separator{
+		grid:    newGrid(opts),
+		courses: courses,
+		offsets: make([]int, len(courses)),
+		placed:  make([]bool, len(courses)),
+	}
+	for _, rule := range rules {
+		if rule.A != rule.B && rule.Separation > 0 {
+			s.rules = append(s.rules, rule)
+		}
+	}
+	return s
+}
+
+// result возвращает времена приема курсов с выбранными сдвигами
+func (s *separator) result() [][]models.TakingTime {
+	result := make([][]models.TakingTime, len(s.courses))
+	for i, course := range s.courses {
+		result[i] = shift(course.Times, s.offsets[i])
+	}
+	return result
+}
+
+// place подбирает сдвиги курсов начиная с depth-го по порядку
+func (s *separator) place(depth int) bool {
+	if depth == len(s.order) {
+		return true
+	}
+
+	i := s.order[depth]
+	for _, offset := range s.candidates(s.courses[i]) {
+		s.steps++
+		if s.steps > maxSeparateSteps {
+			return false
+		}
+
+		s.offsets[i] = offset
+		if rule, ok := s.violated(i); ok {
+			if depth >= s.depth {
+				s.depth, s.failed = depth, rule
+			}
+			continue
+		}
+
+		s.placed[i] = true
+		if s.place(depth + 1) {
+			return true
+		}
+		s.placed[i] = false
+	}
+	return false
+}
+
+// candidates возвращает возможные сдвиги курса от меньшего к большему
+func (s *separator) candidates(course Course) []int {
+	if !course.Movable || len(course.Times) == 0 {
+		return []int{0}
+	}
+
+	first := minuteOf(course.Times[0])
+	last := minuteOf(course.Times[len(course.Times)-1])
+	// Приемы должны остаться в дневном окне на тех же шагах сетки
+	lowest := s.start - first
+	highest := s.start + (s.slots()-1)*s.step - last
+
+	offsets := []int{0}
+	for offset := s.step; offset <= max(-lowest, highest); offset += s.step {
+		if -offset >= lowest {
+			offsets = append(offsets, -offset)
+		}
+		if offset <= highest {
+			offsets = append(offsets, offset)
+		}
+	}
+	return offsets
+}
+
+// violated проверяет правила между курсом i и уже размещенными курсами
+func (s *separator) violated(i int) (Rule, bool) {
+	for _, rule := range s.rules {
+		other := -1
+		switch i {
+		case rule.A:
+			other = rule.B
+		case rule.B:
+			other = rule.A
+		}
+		if other < 0 || !s.placed[other] {
+			continue
+		}
+
+		separation := int(rule.Separation / time.Minute)
+		for _, a := range s.courses[i].Times {
+			for _, b := range s.courses[other].Times {
+				if distance(minuteOf(a)+s.offsets[i], minuteOf(b)+s.offsets[other]) < separation {
+					return rule, true
+				}
+			}
+		}
+	}
+	return Rule{}, false
+}
+
+// distance возвращает промежуток между двумя временами суток с учетом
+// перехода через полночь
+func distance(a, b int) int {
+	d := a - b
+	if d < 0 {
+		d = -d
+	}
+	return min(d, 24*60-d)
+}
+
+// shift сдвигает времена приема на заданное число минут
+func shift(times []models.TakingTime, offset int) []models.TakingTime {
+	shifted := make([]models.TakingTime, 0, len(times))
+	for _, t := range times {
+		m := minuteOf(t) + offset
+		shifted = append(shifted, models.TakingTime{Hour: m / 60, Minute: m % 60})
+	}
+	sort.Slice(shifted, func(i, j int) bool {
+		return minuteOf(shifted[i]) < minuteOf(shifted[j])
+	})
+	return shifted
+}
+
+// minuteOf возвращает номер минуты от начала суток
+func minuteOf(t models.TakingTime) int {
+	return t.Hour*60 + t.Minute
+}
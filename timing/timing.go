@@ -0,0 +1,222 @@
+package timing
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"take-a-pill/models"
+)
+
+// Способы распределения приемов по дню
+const (
+	// Равномерно по дневному окну
+	StrategyEven = "even"
+	// Первый прием сразу после начала дня, остальные через равные промежутки
+	StrategyFrontLoaded = "front_loaded"
+	// Во время приемов пищи, остальные приемы - в самых длинных промежутках между ними
+	StrategyMealAnchored = "meal_anchored"
+)
+
+var (
+	// Разных времен приема с заданным шагом в дневном окне меньше, чем приемов в день
+	ErrTooFrequent = errors.New("приемы не помещаются в дневное окно")
+	// Способ распределения приемов не известен
+	ErrUnknownStrategy = errors.New("неизвестный способ распределения приемов")
+)
+
+// Options задает дневное окно и шаг, с которым назначаются приемы
+type Options struct {
+	// Начало дневного окна от полуночи
+	DayStart time.Duration
+	// Конец дневного окна от полуночи, в само это время прием не назначается
+	DayEnd time.Duration
+	// Шаг, до которого округляется время приема. 0 - одна минута.
+	Granularity time.Duration
+	// Время приемов пищи от полуночи
+	Meals []time.Duration
+}
+
+// Distributor распределяет приемы лекарства по времени суток
+type Distributor interface {
+	// Distribute возвращает ровно frequency разных времен приема по возрастанию
+	// или ErrTooFrequent, если столько приемов не помещается в дневное окно
+	Distribute(frequency int) ([]models.TakingTime, error)
+}
+
+// Valid проверяет, что способ распределения приемов известен
+func Valid(strategy string) bool {
+	switch strategy {
+	case StrategyEven, StrategyFrontLoaded, StrategyMealAnchored:
+		return true
+	}
+	return false
+}
+
+// New возвращает распределение приемов заданным способом
+func New(strategy string, opts Options) (Distributor, error) {
+	g := newGrid(opts)
+	switch strategy {
+	case StrategyEven:
+		return even{g}, nil
+	case StrategyFrontLoaded:
+		return frontLoaded{g}, nil
+	case StrategyMealAnchored:
+		meals := make([]int, 0, len(opts.Meals))
+		for _, meal := range opts.Meals {
+			meals = append(meals, minutes(meal))
+		}
+		sort.Ints(meals)
+		return mealAnchored{grid: g, meals: meals}, nil
+	}
+	return nil, ErrUnknownStrategy
+}
+
+// even распределяет приемы равномерно, не назначая их на самое начало и конец дня
+type even struct {
+	grid
+}
+
+func (d even) Distribute(frequency int) ([]models.TakingTime, error) {
+	// Один прием в день - через час после начала дня
+	if frequency == 1 {
+		return d.place([]int{d.start + 60})
+	}
+
+	interval := (d.end - d.start) / (frequency + 1)
+	ideal := make([]int, 0, frequency)
+	for i := 1; i <= frequency; i++ {
+		ideal = append(ideal, d.start+i*interval)
+	}
+	return d.place(ideal)
+}
+
+// frontLoaded назначает первый прием на начало дня, а остальные через равные
+// промежутки, так что к вечеру остается перерыв
+type frontLoaded struct {
+	grid
+}
+
+func (d frontLoaded) Distribute(frequency int) ([]models.TakingTime, error) {
+	if frequency < 1 {
+		return nil, nil
+	}
+
+	interval := (d.end - d.start) / frequency
+	ideal := make([]int, 0, frequency)
+	for i := 0; i < frequency; i++ {
+		ideal = append(ideal, d.start+i*interval)
+	}
+	return d.place(ideal)
+}
+
+// mealAnchored привязывает приемы к еде. Если приемов меньше, чем приемов
+// пищи, выбираются равномерно отстоящие, начиная с первого. Если больше,
+// лишние приемы ставятся в середину самых длинных промежутков.
+type mealAnchored struct {
+	grid
+	meals []int
+}
+
+func (d mealAnchored) Distribute(frequency int) ([]models.TakingTime, error) {
+	if len(d.meals) == 0 {
+		return even{d.grid}.Distribute(frequency)
+	}
+	if frequency < 1 {
+		return nil, nil
+	}
+
+	if frequency <= len(d.meals) {
+		ideal := make([]int, 0, frequency)
+		for i := 0; i < frequency; i++ {
+			index := 0
+			if frequency > 1 {
+				// Округляем i*(m-1)/(f-1) до ближайшего целого
+				index = (2*i*(len(d.meals)-1) + frequency - 1) / (2 * (frequency - 1))
+			}
+			ideal = append(ideal, d.meals[index])
+		}
+		return d.place(ideal)
+	}
+
+	// Края дневного окна ограничивают промежутки, но сами приемами не являются
+	points := append([]int{d.start}, d.meals...)
+	points = append(points, d.end)
+	for extra := frequency - len(d.meals); extra > 0; extra-- {
+		longest := 0
+		for i := 1; i < len(points)-1; i++ {
+			if points[i+1]-points[i] > points[longest+1]-points[longest] {
+				longest = i
+			}
+		}
+		middle := (points[longest] + points[longest+1]) / 2
+		points = append(points[:longest+1], append([]int{middle}, points[longest+1:]...)...)
+	}
+	return d.place(points[1 : len(points)-1])
+}
+
+// grid - возможные времена приема: от начала дня с заданным шагом до конца дня
+type grid struct {
+	// Начало и конец дня в минутах от полуночи
+	start, end int
+	// Шаг в минутах
+	step int
+}
+
+func newGrid(opts Options) grid {
+	g := grid{start: minutes(opts.DayStart), end: minutes(opts.DayEnd), step: minutes(opts.Granularity)}
+	if g.step < 1 {
+		g.step = 1
+	}
+	return g
+}
+
+// slots возвращает, сколько разных времен приема помещается в дневное окно
+func (g grid) slots() int {
+	if g.end <= g.start {
+		return 0
+	}
+	return (g.end - g.start + g.step - 1) / g.step
+}
+
+// place округляет желаемые времена приема (в минутах от полуночи, по
+// возрастанию) до ближайших времен сетки. Совпавшие после округления
+// времена раздвигаются на соседние свободные, поэтому все времена разные.
+func (g grid) place(ideal []int) ([]models.TakingTime, error) {
+	slots := g.slots()
+	if len(ideal) > slots {
+		return nil, ErrTooFrequent
+	}
+
+	indexes := make([]int, len(ideal))
+	for i, m := range ideal {
+		index := (m - g.start + g.step/2) / g.step
+		if m < g.start {
+			index = 0
+		}
+		indexes[i] = min(index, slots-1)
+		if i > 0 && indexes[i] <= indexes[i-1] {
+			indexes[i] = indexes[i-1] + 1
+		}
+	}
+	// После сдвига вперед последние времена могли выйти за конец дня
+	for i := len(indexes) - 1; i >= 0; i-- {
+		limit := slots - 1
+		if i < len(indexes)-1 {
+			limit = indexes[i+1] - 1
+		}
+		indexes[i] = min(indexes[i], limit)
+	}
+
+	times := make([]models.TakingTime, 0, len(indexes))
+	for _, index := range indexes {
+		m := g.start + index*g.step
+		times = append(times, models.TakingTime{Hour: m / 60, Minute: m % 60})
+	}
+	return times, nil
+}
+
+// minutes переводит время от полуночи в целые минуты
+func minutes(d time.Duration) int {
+	return int(d / time.Minute)
+}
@@ -0,0 +1,116 @@
+// Package tracing настраивает трассировку запросов через OpenTelemetry
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"take-a-pill/logging"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Куда отправлять трассировки
+const (
+	// Трассировки не отправляются, но контекст трассировки передается дальше
+	ExporterNone = ""
+	// Трассировки печатаются в stdout, для локальной отладки
+	ExporterStdout = "stdout"
+	// Трассировки отправляются коллектору по OTLP/HTTP
+	ExporterOTLP = "otlp"
+)
+
+// Options - настройки трассировки
+type Options struct {
+	// Куда отправлять трассировки: "", stdout или otlp
+	Exporter string
+	// Адрес коллектора OTLP (host:port). Если не задан, берется из
+	// переменных окружения OTEL_EXPORTER_OTLP_*, по умолчанию localhost:4318.
+	Endpoint string
+	// Имя сервиса в трассировках
+	ServiceName string
+	// Ключ для хеширования ID пользователей в атрибутах
+	HashKey string
+	// Куда печатать трассировки для stdout (по умолчанию os.Stdout)
+	Output io.Writer
+}
+
+// Ключ для хеширования ID пользователей. До настройки выбирается случайно.
+var hashKey atomic.Value
+
+func init() {
+	key := make([]byte, 32)
+	rand.Read(key)
+	hashKey.Store(key)
+}
+
+// Setup настраивает глобальный провайдер трассировок и передачу контекста
+// трассировки в заголовках W3C traceparent и tracestate. Возвращает функцию,
+// которая отправляет оставшиеся трассировки и останавливает провайдер.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	if opts.HashKey != "" {
+		hashKey.Store([]byte(opts.HashKey))
+	}
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch opts.Exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		output := opts.Output
+		if output == nil {
+			output = os.Stdout
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(output))
+	case ExporterOTLP:
+		var clientOpts []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			clientOpts = append(clientOpts, otlptracehttp.WithEndpoint(opts.Endpoint), otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, clientOpts...)
+	default:
+		return nil, fmt.Errorf("неизвестный способ отправки трассировок %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(opts.ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// UserHash возвращает атрибут с хешем ID пользователя.
+// Сам ID в трассировки не попадает.
+func UserHash(userID string) attribute.KeyValue {
+	return attribute.String("user.hash", logging.HashUserID(hashKey.Load().([]byte), userID))
+}
+
+// ScheduleID возвращает атрибут с ID расписания
+func ScheduleID(scheduleID string) attribute.KeyValue {
+	return attribute.String("schedule.id", scheduleID)
+}
+
+// ScheduleCount возвращает атрибут с числом расписаний, обработанных операцией
+func ScheduleCount(count int) attribute.KeyValue {
+	return attribute.Int("schedule.count", count)
+}
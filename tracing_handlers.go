@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net/http"
+
+	"take-a-pill/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Трассировщик обработчиков API
+var tracer = otel.Tracer("take-a-pill")
+
+// Имя сервиса в трассировках
+const serviceName = "take-a-pill"
+
+// annotateSpan добавляет к спану запроса хеш пользователя из параметров запроса
+func annotateSpan(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userID := r.URL.Query().Get("user_id"); userID != "" {
+			trace.SpanFromContext(r.Context()).SetAttributes(tracing.UserHash(userID))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,31 +1,246 @@
 package validation
 
 import (
-	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"take-a-pill/i18n"
 	"take-a-pill/models"
+	"take-a-pill/timing"
 )
 
+// Коды ошибок валидации полей
+const (
+	// Обязательное поле не заполнено
+	CodeRequired = "required"
+	// Значение вне допустимого диапазона
+	CodeOutOfRange = "out_of_range"
+	// Значение не может быть отрицательным
+	CodeNegative = "negative"
+	// Значение некорректно
+	CodeInvalid = "invalid"
+	// Столько приемов в день не помещается в дневное окно
+	CodeTooFrequent = "too_frequent"
+)
+
+// FieldError описывает ошибку в одном поле запроса
+type FieldError struct {
+	// Название поля в JSON
+	Field string `json:"field"`
+	// Машиночитаемый код ошибки
+	Code string `json:"code"`
+	// Текст ошибки
+	Message string `json:"message"`
+}
+
+// Localize возвращает текст ошибки на заданном языке
+func (e FieldError) Localize(lang i18n.Lang) string {
+	// Для вложенных полей вроде "inventory.pack_size" текст зависит только от самого поля
+	field := e.Field[strings.LastIndex(e.Field, ".")+1:]
+	if field == "" {
+		field = "request"
+	}
+	return i18n.T(lang, "validation."+field+"."+e.Code)
+}
+
+// Errors содержит все ошибки валидации запроса
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		messages = append(messages, fieldErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Localize возвращает копию ошибок с текстами на заданном языке
+func (e Errors) Localize(lang i18n.Lang) Errors {
+	localized := make(Errors, len(e))
+	for i, fieldErr := range e {
+		fieldErr.Message = fieldErr.Localize(lang)
+		localized[i] = fieldErr
+	}
+	return localized
+}
+
+// FieldErr возвращает ошибку одного поля с текстом на языке по умолчанию.
+// Нужна для проверок, которые выполняются вне этого пакета.
+func FieldErr(field, code string) error {
+	var errs Errors
+	errs.add(field, code)
+	return errs
+}
+
+// add добавляет ошибку поля с текстом на языке по умолчанию
+func (e *Errors) add(field, code string) {
+	fieldErr := FieldError{Field: field, Code: code}
+	fieldErr.Message = fieldErr.Localize(i18n.Default)
+	*e = append(*e, fieldErr)
+}
+
+// err возвращает nil, если ошибок нет
+func (e Errors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
 // ValidateScheduleRequest проверяет корректность данных запроса на создание расписания
+// и возвращает сразу все найденные ошибки
 func ValidateScheduleRequest(req *models.ScheduleRequest) error {
+	var errs Errors
 	if req == nil {
-		return errors.New("запрос не может быть пустым")
+		errs.add("", CodeRequired)
+		return errs
 	}
 
 	if req.UserID == "" {
-		return errors.New("не указан идентификатор пользователя")
+		errs.add("user_id", CodeRequired)
 	}
 
 	if req.MedicineName == "" {
-		return errors.New("не указано название лекарства")
+		errs.add("medicine_name", CodeRequired)
 	}
 
 	if req.Frequency < 1 || req.Frequency > 24 {
-		return errors.New("частота приема должна быть от 1 до 24 раз в день")
+		errs.add("frequency", CodeOutOfRange)
 	}
 
 	if req.Duration < 0 {
-		return errors.New("продолжительность лечения не может быть отрицательной")
+		errs.add("duration", CodeNegative)
+	}
+
+	if req.Distribution != "" && !timing.Valid(req.Distribution) {
+		errs.add("distribution", CodeInvalid)
+	}
+
+	if len(req.MealDoses) > 0 {
+		// Приемов в день столько же, сколько приемов относительно еды
+		if req.Frequency != len(req.MealDoses) {
+			errs.add("frequency", CodeInvalid)
+		}
+		errs = append(errs, mealDoseErrors(req.MealDoses)...)
+	}
+
+	if req.Inventory != nil {
+		errs = append(errs, inventoryErrors(req.Inventory, "inventory.")...)
+	}
+
+	return errs.err()
+}
+
+// Максимальный промежуток между приемом лекарства и едой в минутах
+const maxMealOffsetMinutes = 240
+
+// mealDoseErrors собирает ошибки в приемах относительно еды
+func mealDoseErrors(doses []models.MealDose) Errors {
+	var errs Errors
+
+	for i, dose := range doses {
+		prefix := fmt.Sprintf("meal_doses[%d].", i)
+		if !slices.Contains(models.Meals, dose.Meal) {
+			errs.add(prefix+"meal", CodeInvalid)
+		}
+
+		switch dose.Relation {
+		case models.MealRelationBefore, models.MealRelationAfter:
+			if dose.OffsetMinutes < 0 || dose.OffsetMinutes > maxMealOffsetMinutes {
+				errs.add(prefix+"offset_minutes", CodeOutOfRange)
+			}
+		case models.MealRelationWith:
+			if dose.OffsetMinutes != 0 {
+				errs.add(prefix+"offset_minutes", CodeInvalid)
+			}
+		default:
+			errs.add(prefix+"relation", CodeInvalid)
+		}
+	}
+
+	return errs
+}
+
+// ValidateInventory проверяет корректность данных о запасе таблеток
+func ValidateInventory(inv *models.Inventory) error {
+	return inventoryErrors(inv, "").err()
+}
+
+// inventoryErrors собирает ошибки в данных о запасе, добавляя к полям префикс
+func inventoryErrors(inv *models.Inventory, prefix string) Errors {
+	var errs Errors
+
+	if inv.PillsOnHand < 0 {
+		errs.add(prefix+"pills_on_hand", CodeNegative)
+	}
+
+	if inv.PackSize < 0 {
+		errs.add(prefix+"pack_size", CodeNegative)
+	}
+
+	if inv.PillsPerDose < 0 {
+		errs.add(prefix+"pills_per_dose", CodeNegative)
+	}
+
+	return errs
+}
+
+// ValidatePrescriptionRequest проверяет корректность данных запроса на создание рецепта
+// и возвращает сразу все найденные ошибки
+func ValidatePrescriptionRequest(req *models.PrescriptionRequest) error {
+	var errs Errors
+	if req == nil {
+		errs.add("", CodeRequired)
+		return errs
+	}
+
+	if req.UserID == "" {
+		errs.add("user_id", CodeRequired)
+	}
+
+	if req.MedicineName == "" {
+		errs.add("medicine_name", CodeRequired)
+	}
+
+	if req.Prescriber == "" {
+		errs.add("prescriber", CodeRequired)
+	}
+
+	if req.IssueDate.IsZero() {
+		errs.add("issue_date", CodeRequired)
+	}
+
+	if req.ValidUntil.IsZero() {
+		errs.add("valid_until", CodeRequired)
+	} else if !req.IssueDate.IsZero() && !req.ValidUntil.After(req.IssueDate) {
+		errs.add("valid_until", CodeInvalid)
+	}
+
+	if req.Refills < 0 {
+		errs.add("refills", CodeNegative)
+	}
+
+	return errs.err()
+}
+
+// ValidateProfile проверяет корректность настроек пользователя
+func ValidateProfile(profile *models.UserProfile) error {
+	var errs Errors
+
+	if profile.UserID == "" {
+		errs.add("user_id", CodeRequired)
+	}
+
+	if profile.Language != "" && !i18n.Supported(i18n.Lang(profile.Language)) {
+		errs.add("language", CodeInvalid)
+	}
+
+	for meal, t := range profile.Meals {
+		if !slices.Contains(models.Meals, meal) || t.Hour < 0 || t.Hour > 23 || t.Minute < 0 || t.Minute > 59 {
+			errs.add("meals", CodeInvalid)
+			break
+		}
 	}
 
-	return nil
+	return errs.err()
 }